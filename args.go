@@ -70,10 +70,38 @@ func (as *ArgSet) BindPosOnly(interp *Interp, args []*Token) (boundArgs map[stri
 	return as.BindArgs(interp, slices.Insert(args, 1, NewToken("--")))
 }
 
+// BindNamedFirst works the same as BindArgs, but named arguments are only
+// looked for before the first positional argument; it and every argument
+// after it are positional, even if they start with a dash.
+func (as *ArgSet) BindNamedFirst(interp *Interp, args []*Token) (boundArgs map[string]*Token, err error) {
+	for i := 1; i < len(args); i++ {
+		name := args[i].String
+		if name == "--" {
+			break
+		}
+		if !strings.HasPrefix(name, "-") || len(name) < 2 {
+			return as.BindArgs(interp, slices.Insert(slices.Clone(args), i, NewToken("--")))
+		}
+		if arg := as.namedArg(name); arg == nil || !arg.Switch {
+			// skip over the value
+			i++
+		}
+	}
+	return as.BindArgs(interp, args)
+}
+
 // BindArgs uses the defined ArgSet to bind arguments passed in args to a map[string]*Token.
 // This map[string]*Token is suitable for passing to interp.Push() as done when invoking
 // a Proc.
 func (as *ArgSet) BindArgs(interp *Interp, args []*Token) (boundArgs map[string]*Token, err error) {
+	boundArgs, _, err = as.BindArgGroup(interp, args)
+	return
+}
+
+// BindArgGroup works the same as BindArgs, but also returns the ArgGroup that was
+// selected by arity. This lets a caller with several ArgGroups (e.g. an overloaded
+// proc) dispatch on which signature matched.
+func (as *ArgSet) BindArgGroup(interp *Interp, args []*Token) (boundArgs map[string]*Token, ag *ArgGroup, err error) {
 	boundArgs = make(map[string]*Token)
 
 	// Validate Ourself
@@ -88,14 +116,14 @@ func (as *ArgSet) BindArgs(interp *Interp, args []*Token) (boundArgs map[string]
 	}
 
 	// put every argument in namedArgs or posArgs
-	namedArgs, posArgs, err := ParseArgs(args)
+	namedArgs, posArgs, err := ParseArgs(as.expandSwitches(args))
 	if err != nil {
 		return
 	}
 
 	// figure out which ArgGroup to use
 	arity := Arity(len(posArgs))
-	ag := as.GetArgGroup(arity)
+	ag = as.GetArgGroup(arity)
 	if ag == nil {
 		err = fmt.Errorf("expected arity to be one of %v, got %d", as.aritySummary(), arity)
 		return
@@ -191,11 +219,13 @@ func (as *ArgSet) BindArgs(interp *Interp, args []*Token) (boundArgs map[string]
 	// do we have extra supplied args beyond fixed positionals?
 	if ag.PosVariadic {
 		// capture the tail starting at the variadic param (assumed last)
-		start := len(ag.Pos) - 1
+		start, name := len(ag.Pos)-1, "args"
 		if start < 0 {
 			start = 0
+		} else {
+			name = ag.Pos[start].Name
 		}
-		boundArgs["args"] = NewList(posArgs[start:])
+		boundArgs[name] = NewList(posArgs[start:])
 		return
 	}
 
@@ -207,6 +237,50 @@ func (as *ArgSet) BindArgs(interp *Interp, args []*Token) (boundArgs map[string]
 	return
 }
 
+// expandSwitches returns args with TrueToken inserted after each switch so
+// that ParseArgs sees it as a named argument with a value. Values of other
+// named arguments are skipped, so a value that looks like a switch is left
+// alone.
+func (as *ArgSet) expandSwitches(args []*Token) []*Token {
+	out := make([]*Token, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		out = append(out, args[i])
+		name := args[i].String
+		if i == 0 || !strings.HasPrefix(name, "-") || len(name) < 2 {
+			continue
+		}
+		if name == "--" {
+			return append(out, args[i+1:]...)
+		}
+		if arg := as.namedArg(name); arg != nil && arg.Switch {
+			out = append(out, TrueToken)
+		} else if i+1 < len(args) {
+			// skip over the value
+			i++
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// namedArg finds the named argument called name, or that name is a prefix
+// of when Lazy is set, in any of the ArgGroups.
+func (as *ArgSet) namedArg(name string) *Argument {
+	for _, ag := range as.ArgGroups {
+		fullName := name
+		if as.Lazy {
+			var err error
+			if fullName, err = ag.lazyMatch(name); err != nil {
+				continue
+			}
+		}
+		if arg, ok := ag.Named[fullName]; ok {
+			return arg
+		}
+	}
+	return nil
+}
+
 func (as *ArgSet) GetArgGroup(arr Arity) *ArgGroup {
 	switch len(as.ArgGroups) {
 	case 0:
@@ -497,9 +571,10 @@ func (ag *ArgGroup) lazyMatch(name string) (fullName string, err error) {
 func (ag *ArgGroup) Prototype() string {
 	usage := &strings.Builder{}
 
-	// show named args first
-	for _, named := range ag.Named {
-		fmt.Fprintf(usage, "  %s", quoted(named.String()))
+	// show named args first using Names() to
+	// get a sorted list.
+	for _, name := range ag.Names() {
+		fmt.Fprintf(usage, "  %s", quoted(ag.Named[name].String()))
 	}
 
 	// then positional
@@ -515,6 +590,9 @@ type Argument struct {
 	Default *Token
 	Coerce  *Token
 	Help    string
+	// Switch marks a named argument that takes no value: it is bound to
+	// TrueToken when given and to Default (FalseToken if nil) otherwise.
+	Switch bool
 }
 
 type Arity int
@@ -526,6 +604,10 @@ func (arg *Argument) Get(interp *Interp, tok *Token) (ret *Token, err error) {
 		ret = arg.Default
 	}
 
+	if ret == nil && arg.Switch {
+		ret = FalseToken
+	}
+
 	// if we're nil here, that means we weren't given an argument and there's no default
 	if ret == nil {
 		err = ErrArgMissing(arg.Name)
@@ -549,6 +631,10 @@ func (arg *Argument) String() string {
 	b := &strings.Builder{}
 	b.WriteString(arg.Name)
 
+	if arg.Switch {
+		return b.String()
+	}
+
 	if arg.Default == nil && arg.Coerce != nil {
 		b.WriteString(` {}`)
 	}
@@ -571,6 +657,9 @@ func (arg *Argument) String() string {
 func (arg *Argument) HelpLine() string {
 	builder := &strings.Builder{}
 	fmt.Fprintf(builder, "%s\t%s", arg.Name, arg.Help)
+	if arg.Switch {
+		return builder.String()
+	}
 	if arg.Coerce != nil && arg.Coerce.String != "" {
 		fmt.Fprintf(builder, " (%s)", arg.Coerce.String)
 	}
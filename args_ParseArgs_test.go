@@ -169,3 +169,35 @@ func TestParseArgs_NamedValueIsAnotherFlagToken(t *testing.T) {
 	eqPos(t, p, []string{}) // because -b is the value for -a; -c is a new named
 	eqNamed(t, n, map[string]string{"-a": "-b", "-c": "v"})
 }
+
+func TestArgSet_Switch(t *testing.T) {
+	as := NewArgSet("cmd",
+		&Argument{Name: "-verbose", Switch: true},
+		&Argument{Name: "-level", Default: NewToken("1")},
+		Arg("x"),
+	)
+	for _, tc := range []struct {
+		args           []string
+		verbose, level string
+		x              string
+	}{
+		{[]string{"cmd", "a"}, "false", "1", "a"},
+		{[]string{"cmd", "-verbose", "a"}, "true", "1", "a"},
+		{[]string{"cmd", "-v", "-level", "2", "a"}, "true", "2", "a"},
+		{[]string{"cmd", "-level", "-verbose", "a"}, "false", "-verbose", "a"},
+		{[]string{"cmd", "--", "-verbose"}, "false", "1", "-verbose"},
+	} {
+		args := make([]*Token, len(tc.args))
+		for i := range tc.args {
+			args[i] = tok(tc.args[i])
+		}
+		bound, err := as.BindArgs(NewInterp(), args)
+		mustNoErr(t, err)
+		if bound["verbose"].String != tc.verbose || bound["level"].String != tc.level || bound["x"].String != tc.x {
+			t.Errorf("%v: got %v", tc.args, projNamed(bound))
+		}
+	}
+	if !strings.Contains(as.Signature(), "-verbose  ") {
+		t.Errorf("switch shouldn't show a value in %q", as.Signature())
+	}
+}
@@ -0,0 +1,158 @@
+package adz
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// typeInfo is the reflection metadata of a pointer type *T: its exported
+// methods and, when T is a struct, its exported fields. It's computed once
+// per type by typeInfoOf and shared by every GoObject wrapping a *T, so
+// wrapping a value and calling its methods doesn't walk the method set again.
+type typeInfo struct {
+	methods     map[string]*methodInfo
+	methodNames []string // sorted
+	fields      map[string]*fieldInfo
+	fieldNames  []string              // declaration order
+	fieldKeys   map[string]*fieldInfo // by json name, as flatten names them
+}
+
+// methodInfo describes one method. fn is the method expression, taking the
+// receiver as its first argument, so it can be called on any *T.
+type methodInfo struct {
+	name     string
+	fn       reflect.Value
+	in       []reflect.Type // excluding the receiver; for variadic methods the last is the element type
+	variadic bool
+}
+
+type fieldInfo struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	help  string // from the help struct tag
+}
+
+var typeInfoCache sync.Map // reflect.Type -> *typeInfo
+
+// typeInfoOf returns the cached metadata for ptrType, which must be a pointer
+// type, computing it on first use.
+func typeInfoOf(ptrType reflect.Type) *typeInfo {
+	if ti, ok := typeInfoCache.Load(ptrType); ok {
+		return ti.(*typeInfo)
+	}
+
+	ti := &typeInfo{
+		methods:   make(map[string]*methodInfo),
+		fields:    make(map[string]*fieldInfo),
+		fieldKeys: make(map[string]*fieldInfo),
+	}
+
+	for i := 0; i < ptrType.NumMethod(); i++ {
+		m := ptrType.Method(i)
+		if !token.IsExported(m.Name) {
+			continue
+		}
+		mt := m.Type
+		mi := &methodInfo{
+			name:     m.Name,
+			fn:       m.Func,
+			in:       make([]reflect.Type, 0, mt.NumIn()-1),
+			variadic: mt.IsVariadic(),
+		}
+		for j := 1; j < mt.NumIn(); j++ {
+			mi.in = append(mi.in, mt.In(j))
+		}
+		if mi.variadic {
+			mi.in[len(mi.in)-1] = mi.in[len(mi.in)-1].Elem()
+		}
+		ti.methods[m.Name] = mi
+		ti.methodNames = append(ti.methodNames, m.Name)
+	}
+	sort.Strings(ti.methodNames)
+
+	if rt := ptrType.Elem(); rt.Kind() == reflect.Struct {
+		for i := 0; i < rt.NumField(); i++ {
+			sf := rt.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			fi := &fieldInfo{name: sf.Name, index: sf.Index, typ: sf.Type, help: sf.Tag.Get("help")}
+			ti.fields[sf.Name] = fi
+			ti.fieldNames = append(ti.fieldNames, sf.Name)
+			if key, _, skip := parseTag(sf, "json", false); !skip && key != "" {
+				ti.fieldKeys[key] = fi
+			}
+		}
+	}
+
+	actual, _ := typeInfoCache.LoadOrStore(ptrType, ti)
+	return actual.(*typeInfo)
+}
+
+// field looks up a field by the key flatten would give it, falling back to
+// its Go name.
+func (ti *typeInfo) field(key string) *fieldInfo {
+	if fi, ok := ti.fieldKeys[key]; ok {
+		return fi
+	}
+	return ti.fields[key]
+}
+
+// call calls the method on recv, converting args to the parameter types with
+// tokenToValue.
+func (mi *methodInfo) call(interp *Interp, recv reflect.Value, args []*Token) (*Token, error) {
+	if mi.variadic {
+		if fixed := len(mi.in) - 1; len(args) < fixed {
+			return EmptyToken, ErrArgMinimum(fixed, len(args))
+		}
+	} else if len(args) != len(mi.in) {
+		return EmptyToken, ErrArgCount(len(mi.in), len(args))
+	}
+
+	in := make([]reflect.Value, 1, len(args)+1)
+	in[0] = recv
+	for i, arg := range args {
+		typ := mi.in[min(i, len(mi.in)-1)]
+		val, err := tokenToValue(interp, arg, typ)
+		if err != nil {
+			return EmptyToken, fmt.Errorf("%s arg %d: %w", mi.name, i+1, err)
+		}
+		in = append(in, val)
+	}
+
+	return callResults(mi.fn.Call(in))
+}
+
+// tokenToValue converts tok to a value of type typ. It's the one conversion
+// path shared by Wrap, WrapObject and FuncProc: first the scalar conversions
+// of convertTokenTo, then coerceTokenTo, which also understands JSON for
+// composite types, and finally treating tok as a list when typ is a slice.
+func tokenToValue(interp *Interp, tok *Token, typ reflect.Type) (reflect.Value, error) {
+	val, err := convertTokenTo(tok, typ)
+	if err == nil {
+		return val, nil
+	}
+	if v, cerr := coerceTokenTo(interp, tok, typ); cerr == nil {
+		return reflect.ValueOf(v), nil
+	}
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, err
+	}
+	list, lerr := tok.AsList()
+	if lerr != nil {
+		return reflect.Value{}, err
+	}
+	slice := reflect.MakeSlice(typ, len(list), len(list))
+	for i := range list {
+		elem, eerr := tokenToValue(interp, list[i], typ.Elem())
+		if eerr != nil {
+			return reflect.Value{}, fmt.Errorf("element %d: %w", i, eerr)
+		}
+		slice.Index(i).Set(elem)
+	}
+	return slice, nil
+}
@@ -0,0 +1,107 @@
+package adz
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bindPoint struct {
+	X, Y int
+	Tags []string
+}
+
+func (p bindPoint) Sum(extra ...int) int {
+	s := p.X + p.Y
+	for _, e := range extra {
+		s += e
+	}
+	return s
+}
+
+func (p *bindPoint) Move(dx, dy int) {
+	p.X += dx
+	p.Y += dy
+}
+
+func TestBinding_WrapAndWrapObjectAgree(t *testing.T) {
+	for name, tok := range map[string]*Token{
+		"Wrap":       Wrap(bindPoint{X: 1, Y: 2}),
+		"WrapObject": WrapObject(&bindPoint{X: 1, Y: 2}, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			interp := NewInterp()
+			interp.SetVar("p", tok)
+
+			runScripts(t, interp, []scriptTest{
+				{`$p Sum`, "3"},
+				{`$p Sum 10 20`, "33"},
+				{`$p Move 1 1; $p .X`, "2"},
+				{`$p .Y 5; $p Sum`, "7"},
+				{`$p .Tags {a b c}; $p .Tags`, "[a b c]"},
+				{`$p .Tags {["x","y"]}; $p .Tags`, "[x y]"},
+			})
+
+			if _, err := interp.ExecString(`$p Move 1`); err == nil {
+				t.Errorf("expected arity error from Move")
+			}
+			if _, err := interp.ExecString(`$p Sum 1 x`); err == nil || !strings.Contains(err.Error(), "Sum arg 2") {
+				t.Errorf("expected conversion error naming Sum arg 2, got %v", err)
+			}
+			if _, err := interp.ExecString(`$p Nope`); err == nil {
+				t.Errorf("expected error for unknown method")
+			}
+		})
+	}
+}
+
+func TestBinding_TypeInfoCached(t *testing.T) {
+	a := Wrap(bindPoint{}).Data.(*GoObject)
+	b := WrapObject(&bindPoint{}, nil).Data.(*GoObject)
+	if a.info != b.info {
+		t.Errorf("expected GoObjects of the same type to share metadata")
+	}
+	if got := a.info.methodNames; !reflect.DeepEqual(got, []string{"Move", "Sum"}) {
+		t.Errorf("expected methods [Move Sum], got %v", got)
+	}
+	if got := a.info.fieldNames; !reflect.DeepEqual(got, []string{"X", "Y", "Tags"}) {
+		t.Errorf("expected fields [X Y Tags], got %v", got)
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Wrap(bindPoint{X: i})
+	}
+}
+
+func BenchmarkGoObject_Method(b *testing.B) {
+	interp := NewInterp()
+	obj := Wrap(bindPoint{X: 1, Y: 2}).Data.(*GoObject)
+	args := []*Token{NewToken("$p"), NewToken("Sum"), NewToken("3"), NewToken("4")}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := obj.Proc(interp, args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGoObject_Field(b *testing.B) {
+	interp := NewInterp()
+	obj := Wrap(bindPoint{X: 1, Y: 2}).Data.(*GoObject)
+	get := []*Token{NewToken("$p"), NewToken(".X")}
+	set := []*Token{NewToken("$p"), NewToken(".X"), NewToken("7")}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := obj.Proc(interp, set); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := obj.Proc(interp, get); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
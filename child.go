@@ -0,0 +1,331 @@
+package adz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InterpLib holds the subcommands of interp, e.g. InterpLib["eval"]
+// implements interp eval. Each is called with args[0] set to the subcommand
+// name.
+var InterpLib = map[string]Proc{}
+
+// unsafeCommands are left out of safe interpreters. The standard library
+// has no commands reaching the file system or the OS, so that's those that
+// use resources beyond the interpreter's own.
+var unsafeCommands = []string{"go"}
+
+func init() {
+	StdLib["interp"] = ProcInterp
+
+	InterpLib["create"] = ProcInterpCreate
+	InterpLib["eval"] = ProcInterpEval
+	InterpLib["alias"] = ProcInterpAlias
+	InterpLib["delete"] = ProcInterpDelete
+	InterpLib["share"] = ProcInterpShare
+}
+
+// ChildOptions configures an interpreter made by Interp.NewChild.
+type ChildOptions struct {
+	// Safe leaves out commands unfit for untrusted scripts, and gives the
+	// child no stdin, stdout or stderr of its own; set them on the child to
+	// allow it any. The children of a safe interpreter are always safe.
+	Safe bool
+}
+
+// parentLink is how a child interpreter calls into its parent, for
+// aliases.
+type parentLink struct {
+	parent *Interp
+	// the execution context of the parent while it's running interp eval on
+	// the child, and so waiting on it; set while the child's lock is held
+	caller *Interp
+}
+
+// NewChild returns a new interpreter, with namespaces of its own, that can
+// be given aliases to commands of interp; see Alias. Unless it's safe, it
+// shares interp's stdin, stdout and stderr.
+//
+// The child shares interp's lock, so that each can call into the other
+// without deadlocking; only one of them runs at a time.
+func (interp *Interp) NewChild(opts ChildOptions) *Interp {
+	child := NewInterp()
+	child.MaxCallDepth = interp.MaxCallDepth
+	child.link = &parentLink{parent: interp.root}
+	child.Mutex = interp.Mutex
+	child.cancel()
+	child.ctx, child.cancel = context.WithCancel(interp.ctx)
+
+	if opts.Safe || interp.safe {
+		child.safe = true
+		for _, name := range unsafeCommands {
+			delete(child.Namespaces[""].Procs, name)
+		}
+		return child
+	}
+	child.Stdin = interp.Stdin
+	child.Stdout = interp.Stdout
+	child.Stderr = interp.Stderr
+	return child
+}
+
+// Safe reports whether interp was made as a safe interpreter.
+func (interp *Interp) Safe() bool {
+	return interp.safe
+}
+
+// Alias creates the command name in interp, which runs target's command
+// prefix with the alias's args appended. Args and results are passed as
+// strings, as with go, so the two interpreters don't share tokens.
+//
+// target's command runs as a call of its own, unless target is already
+// running the call that led to the alias: when it's interp itself, or
+// interp's parent running interp eval. Then it runs as part of that call,
+// in the scope that's running.
+func (interp *Interp) Alias(name string, target *Interp, prefix ...*Token) error {
+	if len(prefix) == 0 {
+		return ErrArgMissing("target command")
+	}
+	target = target.root
+	link := interp.link
+
+	return interp.Proc(name, func(pinterp *Interp, args []*Token) (*Token, error) {
+		run := target
+		switch {
+		case pinterp.root == target:
+			run = pinterp
+		case link != nil && link.parent == target && link.caller != nil:
+			run = link.caller
+		}
+
+		run, leave := run.enterFrom(pinterp)
+		defer leave()
+
+		cmd := slices.Clone(prefix)
+		for _, arg := range args[1:] {
+			cmd = append(cmd, detach(arg))
+		}
+		ret, err := run.ExecLiteral(cmd)
+		return detach(ret), err
+	})
+}
+
+// evalFrom runs script on interp, a child of caller's interpreter, while
+// caller, an execution context, waits on it.
+func (interp *Interp) evalFrom(caller *Interp, script string) (*Token, error) {
+	interp, leave := interp.enterFrom(caller)
+	defer leave()
+
+	if interp.link != nil && interp.link.parent == caller.root {
+		prev := interp.link.caller
+		interp.link.caller = caller
+		defer func() { interp.link.caller = prev }()
+	}
+
+	ret, err := interp.ExecString(script)
+	if errors.Is(err, ErrFlowControl) {
+		// a break or continue with nothing to break out of
+		err = fmt.Errorf("unexpected %v", err)
+	}
+	return detach(ret), err
+}
+
+// sharedVar is a Getter and Setter holding a variable shared between
+// interpreters, which may be running on different goroutines. Its value is
+// kept as a string, as with go.
+type sharedVar struct {
+	mu  sync.Mutex
+	val *Token
+}
+
+// Token returns a token with its .Data set to sv.
+func (sv *sharedVar) Token() *Token {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return &Token{String: sv.val.String, Data: sv}
+}
+
+func (sv *sharedVar) Get(*Token) (*Token, error) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	// a copy each time, so nobody caches anything in the one shared value
+	return &Token{String: sv.val.String, Data: sv.val.Data}, nil
+}
+
+func (sv *sharedVar) Set(_, val *Token) (*Token, error) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	sv.val = detach(val)
+	return val, nil
+}
+
+// ShareVar makes the variable name of interp and the global variable
+// childName of child one and the same, starting with the value of name.
+func (interp *Interp) ShareVar(name string, child *Interp, childName string) error {
+	interp, leave := interp.enter()
+	defer leave()
+
+	val, err := interp.GetVar(name)
+	if err != nil {
+		if !errors.Is(err, ErrNoVar) {
+			return err
+		}
+		val = EmptyToken
+	}
+	sv := &sharedVar{val: detach(val)}
+	interp.bindVar(name, sv.Token())
+
+	if child.root == interp.root {
+		child = interp
+	}
+	child, leaveChild := child.enterFrom(interp)
+	defer leaveChild()
+	child.bindVar("::"+strings.TrimPrefix(childName, "::"), sv.Token())
+	return nil
+}
+
+// ProcInterp implements interp, which manages child interpreters:
+//
+//	interp subcommand ?arg ...?
+func ProcInterp(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, fmt.Errorf("usage: interp %s ?arg ...?", strings.Join(interpSubcommands(), "|"))
+	}
+	sub, ok := InterpLib[args[1].String]
+	if !ok {
+		return EmptyToken, ErrCommand("interp", fmt.Sprintf("unknown subcommand %q, expected one of %s", args[1].String, strings.Join(interpSubcommands(), ", ")))
+	}
+	return sub(interp, args[1:])
+}
+
+func interpSubcommands() []string {
+	names := make([]string, 0, len(InterpLib))
+	for name := range InterpLib {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// childInterp returns interp's child name, or for an empty name, interp
+// itself.
+func (interp *Interp) childInterp(name string) (*Interp, error) {
+	if name == "" {
+		return interp, nil
+	}
+	child, ok := interp.children[name]
+	if !ok {
+		return nil, fmt.Errorf("no such interp %q", name)
+	}
+	return child, nil
+}
+
+func ProcInterpCreate(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet("interp "+args[0].String,
+		&Argument{Name: "-safe", Switch: true, Help: "Leave out commands unfit for untrusted scripts, and don't give the child this interpreter's stdin, stdout or stderr."},
+		ArgDefault("name", EmptyToken),
+	)
+	as.Help = "Creates a child interpreter, with namespaces of its own, and returns its name. Without {name}, one is made up."
+
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+	name := parsedArgs["name"].String
+	if name == "" {
+		name = interp.Monotonic.Next("interp")
+	}
+	if _, ok := interp.children[name]; ok {
+		return EmptyToken, fmt.Errorf("interp %q already exists", name)
+	}
+
+	interp.children[name] = interp.NewChild(ChildOptions{Safe: parsedArgs["safe"].Data.(bool)})
+	return NewTokenString(name), nil
+}
+
+// interp eval name script runs script in the child name, returning its
+// result.
+func ProcInterpEval(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	child, ok := interp.children[args[1].String]
+	if !ok {
+		return EmptyToken, fmt.Errorf("no such interp %q", args[1].String)
+	}
+	return child.evalFrom(interp, args[2].String)
+}
+
+// interp alias child cmd parent target ?arg ...? creates the command cmd in
+// child, which runs target in parent with args prepended to its own. child
+// and parent name children of this interpreter, or this one if empty.
+func ProcInterpAlias(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 5 {
+		return EmptyToken, ErrArgMinimum(4, len(args)-1)
+	}
+	src, err := interp.childInterp(args[1].String)
+	if err != nil {
+		return EmptyToken, err
+	}
+	target, err := interp.childInterp(args[3].String)
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	prefix := make([]*Token, 0, len(args)-4)
+	for _, arg := range args[4:] {
+		prefix = append(prefix, detach(arg))
+	}
+
+	src, leave := src.enterFrom(interp)
+	defer leave()
+	if err := src.Alias(args[2].String, target, prefix...); err != nil {
+		return EmptyToken, err
+	}
+	return args[2], nil
+}
+
+// interp delete ?name ...? deletes the children named, closing them; see
+// Interp.Close.
+func ProcInterpDelete(interp *Interp, args []*Token) (*Token, error) {
+	for _, name := range args[1:] {
+		if _, ok := interp.children[name.String]; !ok {
+			return EmptyToken, fmt.Errorf("no such interp %q", name.String)
+		}
+	}
+	for _, name := range args[1:] {
+		if child, ok := interp.children[name.String]; ok {
+			child.closeFrom(interp)
+			delete(interp.children, name.String)
+		}
+	}
+	return EmptyToken, nil
+}
+
+// interp share child varName ?childVarName? shares the variable varName
+// with child, as its global variable childVarName, by default the same
+// name. Changes either makes are seen by the other.
+func ProcInterpShare(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return EmptyToken, ErrArgCount("2 or 3", len(args)-1)
+	}
+	child, ok := interp.children[args[1].String]
+	if !ok {
+		return EmptyToken, fmt.Errorf("no such interp %q", args[1].String)
+	}
+	name := args[2].String
+	_, childName := identifierParts(name)
+	if len(args) == 4 {
+		childName = args[3].String
+	}
+	if err := interp.ShareVar(name, child, childName); err != nil {
+		return EmptyToken, err
+	}
+	return EmptyToken, nil
+}
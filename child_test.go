@@ -0,0 +1,170 @@
+package adz
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInterpChild(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`interp create c; interp eval c {+ 1 2}`, "3"},
+		{`interp create`, "interp#0"},
+		// children have namespaces of their own
+		{`set x parent; interp create c; interp eval c {set x child}; list $x [interp eval c {return $x}]`, "parent child"},
+		{`proc p {} {}; interp create c; interp eval c {catch {p}}`, "true"},
+		// aliases call back into the parent, in the scope running interp eval
+		{`proc double {x} { * $x 2 }; interp create c; interp alias c dbl {} double; interp eval c {dbl 21}`, "42"},
+		{`interp create c; interp alias c app {} list::append log; interp eval c {app a; app b}; return $log`, "a b"},
+		{`proc f {} { interp alias c seen {} set local; interp eval c {seen yes}; return $local }; interp create c; f`, "yes"},
+		// args are appended to the target prefix
+		{`interp create c; interp alias c greet {} list hello; interp eval c {greet world}`, "hello world"},
+		// alias between children, and within an interpreter
+		{`interp create a; interp create b; interp eval b {proc hi {} { return from-b }}; interp alias a hi b hi; interp eval a {hi}`, "from-b"},
+		{`interp alias {} l2 {} list x; l2 y`, "x y"},
+		// shared variables
+		{`set n 1; interp create c; interp share c n; interp eval c {set n 2}; return $n`, "2"},
+		{`set n 1; interp create c; interp share c n m; set n 5; interp eval c {return $m}`, "5"},
+		{`interp create c; interp share c fresh; interp eval c {set fresh new}; return $fresh`, "new"},
+		// grandchildren
+		{`interp create c; interp eval c {interp create g; interp eval g {list deep}}`, "deep"},
+		{`interp create c; interp delete c; catch {interp eval c {}}`, "true"},
+	})
+
+	for _, script := range []string{
+		`interp`,
+		`interp bogus`,
+		`interp create c; interp create c`,
+		`interp eval nope {}`,
+		`interp create c; interp eval c {throw oops}`,
+		`interp create c; interp eval c {break}`,
+		`interp create c; interp alias c x nope y`,
+		`interp create c; interp alias c x {}`,
+		`interp delete nope`,
+		`interp share nope x`,
+	} {
+		interp := NewInterp()
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestInterpChild_Safe(t *testing.T) {
+	interp := NewInterp()
+	var out bytes.Buffer
+	interp.Stdout = &out
+
+	mustRun(t, interp, `interp create -safe s; interp create u`)
+	if got := mustRun(t, interp, `interp eval s {catch {go {}}}`); got != "true" {
+		t.Error("expected go to be missing from a safe interp")
+	}
+	if got := mustRun(t, interp, `interp eval u {catch {chan::recv [go {}]}}`); got != "false" {
+		t.Error("expected go in an interp that isn't safe")
+	}
+	// safe children of safe interps
+	if got := mustRun(t, interp, `interp eval s {interp create g; interp eval g {catch {go {}}}}`); got != "true" {
+		t.Error("expected a safe interp's children to be safe")
+	}
+
+	// no output of its own, but a vetted alias can give it some
+	mustRun(t, interp, `interp eval s {println hidden}; interp eval u {println shown}`)
+	mustRun(t, interp, `proc log {msg} { println "log: $msg" }; interp alias s log {} log; interp eval s {log ok}`)
+	if got := out.String(); got != "shown\nlog: ok\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestInterp_NewChild(t *testing.T) {
+	parent := NewInterp()
+	mustRun(t, parent, `set count 0; proc bump {by} { incr ::count $by }`)
+
+	child := parent.NewChild(ChildOptions{Safe: true})
+	if !child.Safe() {
+		t.Error("expected a safe child")
+	}
+	if err := child.Alias("bump", parent, NewTokenString("bump")); err != nil {
+		t.Fatal(err)
+	}
+	if err := parent.ShareVar("count", child, "total"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the child can run on its own, locking its parent as it calls into it
+	if got := mustRun(t, child, `bump 2; bump 3; return $total`); got != "5" {
+		t.Errorf("expected 5, got %q", got)
+	}
+	if got := mustRun(t, parent, `return $count`); got != "5" {
+		t.Errorf("expected 5, got %q", got)
+	}
+
+	if _, err := child.ExecString(`return $::count`); err == nil || !strings.Contains(err.Error(), "count") {
+		t.Errorf("expected the parent's other variables to be hidden, got %v", err)
+	}
+}
+
+func TestInterp_NewChild_Concurrent(t *testing.T) {
+	const workers, iterations = 4, 50
+
+	parent := NewInterp()
+	mustRun(t, parent, `set count 0; proc bump {} { incr ::count }; interp create c; interp alias c bump {} bump`)
+	child := parent.children["c"]
+
+	// the parent calling into the child and the child calling into the
+	// parent, from different goroutines
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				var err error
+				if w%2 == 0 {
+					_, err = parent.ExecString(`interp eval c {bump}`)
+				} else {
+					_, err = child.ExecString(`bump`)
+				}
+				if err != nil {
+					t.Errorf("worker %d: unexpected error: %v", w, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := mustRun(t, parent, `return $count`); got != "200" {
+		t.Errorf("expected 200, got %q", got)
+	}
+}
+
+func TestInterpChild_DeleteEnds(t *testing.T) {
+	before := runtime.NumGoroutine()
+	interp := NewInterp()
+	mustRun(t, interp, `interp create c
+		interp eval c {
+			proc gen {} { while true { yield 1 } }
+			coroutine g gen
+			interp create gc
+			interp eval gc { proc gen {} { while true { yield 1 } }; coroutine g gen }
+			set ch [chan::new]
+			go -vars ch { chan::recv $ch }
+			go -vars ch { select recv $ch v {} }
+			go { go -vars ch { chan::send $ch x } }
+		}
+		interp delete c`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running", n-before)
+	}
+	if _, err := interp.ExecString(`interp eval c {}`); err == nil {
+		t.Error("expected c to be gone")
+	}
+}
@@ -0,0 +1,456 @@
+package adz
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	StdLib["class"] = ProcClass
+	InfoLib["classes"] = procInfoClasses
+	InfoLib["class"] = procInfoClass
+	InfoLib["object"] = procInfoObject
+}
+
+// Class is a class defined by a script with class define.
+type Class struct {
+	Name   string // fully qualified
+	Super  *Class
+	fields []string // declaration order
+	defs   map[string]*Token
+	meths  map[string]*classMethod
+
+	// ns is the namespace the class was defined in; methods run there.
+	ns *Namespace
+	// the interpreter the class was defined in; see Interp.foreign
+	interp *Interp
+}
+
+type classMethod struct {
+	args *ArgSet
+	body *Token
+}
+
+// Object is an instance of a Class. Tokens holding an Object are invocable:
+//
+//	$obj method ?arg ...?  ;# call a method
+//	$obj .field ?value?    ;# get or set a field
+type Object struct {
+	Class  *Class
+	Fields map[string]*Token
+	tok    *Token
+}
+
+// method finds the method called name, searching up through superclasses.
+func (c *Class) method(name string) (*classMethod, bool) {
+	for ; c != nil; c = c.Super {
+		if m, ok := c.meths[name]; ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Fields returns the names of all fields, including inherited ones, with the
+// superclass's first.
+func (c *Class) Fields() []string {
+	if c.Super == nil {
+		return c.fields
+	}
+	fields := c.Super.Fields()
+	for _, f := range c.fields {
+		if _, ok := c.Super.field(f); !ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// field returns the default value of the field called name.
+func (c *Class) field(name string) (*Token, bool) {
+	for ; c != nil; c = c.Super {
+		if def, ok := c.defs[name]; ok {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+// Methods returns the sorted names of all methods, including inherited ones.
+func (c *Class) Methods() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for cl := c; cl != nil; cl = cl.Super {
+		for name := range cl.meths {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Is reports whether c is other or inherits from it.
+func (c *Class) Is(other *Class) bool {
+	for ; c != nil; c = c.Super {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns a token holding a new instance of c, with every field set to its
+// default.
+func (c *Class) New(interp *Interp) *Token {
+	obj := &Object{
+		Class:  c,
+		Fields: make(map[string]*Token),
+	}
+	for _, f := range c.Fields() {
+		obj.Fields[f], _ = c.field(f)
+	}
+	obj.tok = &Token{
+		String: interp.Monotonic.Next(c.Name),
+		Data:   obj,
+	}
+	return obj.tok
+}
+
+// Proc implements Procer; see ProcClass.
+func (c *Class) Proc(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, 0)
+	}
+	if interp.foreign(c.interp) {
+		return EmptyToken, errForeign(c.Name)
+	}
+	switch args[1].String {
+	case "new":
+		if len(args)%2 != 0 {
+			return EmptyToken, fmt.Errorf("%s new: expected -field value pairs", c.Name)
+		}
+		tok := c.New(interp)
+		obj := tok.Data.(*Object)
+		for i := 2; i < len(args); i += 2 {
+			if _, err := obj.setField(strings.TrimPrefix(args[i].String, "-"), args[i+1]); err != nil {
+				return EmptyToken, fmt.Errorf("%s new: %w", c.Name, err)
+			}
+		}
+		return tok, nil
+	case "fields":
+		return NewList(NewTokenListString(c.Fields())), nil
+	case "methods":
+		return NewList(NewTokenListString(c.Methods())), nil
+	}
+	return EmptyToken, ErrCommand(c.Name, fmt.Sprintf("unknown subcommand %q", args[1].String))
+}
+
+// Proc implements Procer.
+func (obj *Object) Proc(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, 0)
+	}
+	if interp.foreign(obj.Class.interp) {
+		return EmptyToken, errForeign(obj.tok.String)
+	}
+	name := args[1].String
+	tail := interp.tailcalled(args)
+
+	if strings.HasPrefix(name, ".") {
+		switch len(args) {
+		case 2:
+			return obj.getField(name[1:])
+		case 3:
+			return obj.setField(name[1:], args[2])
+		default:
+			return EmptyToken, ErrArgCount(1, len(args)-2)
+		}
+	}
+
+	m, ok := obj.Class.method(name)
+	if !ok {
+		return EmptyToken, ErrCommand(obj.Class.Name, fmt.Sprintf("no such method %q", name))
+	}
+	return obj.call(interp, m, args[1:], tail)
+}
+
+// Interface returns the Object itself.
+func (obj *Object) Interface() any {
+	return obj
+}
+
+func (obj *Object) getField(name string) (*Token, error) {
+	if val, ok := obj.Fields[name]; ok {
+		return val, nil
+	}
+	return EmptyToken, ErrCommand(obj.Class.Name, fmt.Sprintf("no such field %q", name))
+}
+
+func (obj *Object) setField(name string, val *Token) (*Token, error) {
+	if _, ok := obj.Fields[name]; !ok {
+		return EmptyToken, ErrCommand(obj.Class.Name, fmt.Sprintf("no such field %q", name))
+	}
+	obj.Fields[name] = val
+	return val, nil
+}
+
+// call runs method m with args, where args[0] is the method name. The body
+// runs in the class's namespace with the variable self set to the object and
+// the command my available for field access.
+func (obj *Object) call(interp *Interp, m *classMethod, args []*Token, tail bool) (*Token, error) {
+	ret, err := func() (*Token, error) {
+		bound, err := m.args.BindArgs(interp, args)
+		if err != nil {
+			m.args.ShowUsage(interp.Stderr)
+			return EmptyToken, err
+		}
+		bound["self"] = obj.tok
+
+		interp.Push(&Frame{
+			localNamespace: obj.Class.ns,
+			localProcs:     map[string]Proc{"my": obj.procMy},
+			localVars:      bound,
+		})
+		defer interp.Pop()
+
+		return interp.ExecToken(m.body)
+	}()
+
+	ret, err = interp.trampoline(ret, err, tail)
+	if err == ErrReturn {
+		err = nil
+	}
+	return ret, err
+}
+
+// procMy implements my, available within methods:
+//
+//	my field ?value?
+func (obj *Object) procMy(interp *Interp, args []*Token) (*Token, error) {
+	switch len(args) {
+	case 2:
+		return obj.getField(args[1].String)
+	case 3:
+		return obj.setField(args[1].String, args[2])
+	}
+	return EmptyToken, ErrArgCount(1, len(args)-1)
+}
+
+// ProcClass implements class:
+//
+//	class define Name {
+//		superclass Base
+//		field name ?default?
+//		method name {args} {body}
+//	}
+//
+// The class is then a command in the current namespace: Name new ?-field
+// value ...? creates an instance, and Name fields and Name methods list its
+// fields and methods.
+func ProcClass(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 4 || args[1].String != "define" {
+		return EmptyToken, fmt.Errorf("usage: class define name body")
+	}
+
+	ns, id := interp.Frame.localNamespace, args[2].String
+	if isQualified(id) {
+		ns, id, _ = interp.ResolveIdentifier(id, true)
+	}
+	cls := &Class{
+		Name:   ns.Qualified(id),
+		defs:   make(map[string]*Token),
+		meths:  make(map[string]*classMethod),
+		ns:     ns,
+		interp: interp,
+	}
+
+	interp.Push(&Frame{
+		localNamespace: ns,
+		localVars:      make(map[string]*Token),
+		localProcs: map[string]Proc{
+			"superclass": cls.procSuperclass,
+			"field":      cls.procField,
+			"method":     cls.procMethod,
+		},
+	})
+	_, err := interp.ExecToken(args[3])
+	interp.Pop()
+	if err != nil {
+		return EmptyToken, fmt.Errorf("class %s: %w", id, err)
+	}
+
+	interp.classes[cls.Name] = cls
+	ns.Procs[id] = cls.Proc
+	interp.dropCoroutine(ns.Qualified(id))
+	return NewTokenString(cls.Name), nil
+}
+
+func (c *Class) procSuperclass(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	super, err := interp.lookupClass(args[1].String)
+	if err != nil {
+		return EmptyToken, err
+	}
+	c.Super = super
+	return EmptyToken, nil
+}
+
+func (c *Class) procField(interp *Interp, args []*Token) (*Token, error) {
+	switch len(args) {
+	case 2:
+		c.addField(args[1].String, EmptyToken)
+	case 3:
+		c.addField(args[1].String, args[2])
+	default:
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	return EmptyToken, nil
+}
+
+func (c *Class) addField(name string, def *Token) {
+	if _, ok := c.defs[name]; !ok {
+		c.fields = append(c.fields, name)
+	}
+	c.defs[name] = def
+}
+
+func (c *Class) procMethod(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 4 {
+		return EmptyToken, ErrArgCount(3, len(args)-1)
+	}
+	as := NewArgSet(args[1].String)
+	if err := as.ParseProto(args[2]); err != nil {
+		return EmptyToken, fmt.Errorf("method %s: %w", args[1].String, err)
+	}
+	c.meths[args[1].String] = &classMethod{args: as, body: args[3]}
+	return EmptyToken, nil
+}
+
+// lookupClass finds a class by name, relative to the current namespace and
+// then the global one.
+func (interp *Interp) lookupClass(name string) (*Class, error) {
+	candidates := []string{interp.Frame.localNamespace.Qualified(name), "::" + name}
+	if isQualified(name) {
+		candidates = []string{"::" + strings.TrimPrefix(name, "::")}
+	}
+	for _, qual := range candidates {
+		if cls, ok := interp.classes[qual]; ok {
+			return cls, nil
+		}
+	}
+	return nil, fmt.Errorf("no such class %q", name)
+}
+
+// Anoint makes the variable varName an instance of className. If the variable
+// already holds a key/value list, matching fields are seeded from it.
+func (interp *Interp) Anoint(varName, className string) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
+	cls, err := interp.lookupClass(className)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("cannot anoint %s with %s: %w", varName, className, err)
+	}
+	obj := cls.New(interp)
+
+	// if variable already exists, use its value
+	tok, err := interp.GetVar(varName)
+	switch {
+	case errors.Is(err, ErrNoVar):
+	case err != nil:
+		return EmptyToken, fmt.Errorf("cannot anoint %s with %s: %w", varName, className, err)
+	case tok.String != "":
+		kv, err := tok.AsMap()
+		if err != nil {
+			return EmptyToken, fmt.Errorf("cannot anoint %s with %s: %w", varName, className, err)
+		}
+		fields := obj.Data.(*Object).Fields
+		for k, v := range kv {
+			if _, ok := fields[k]; ok {
+				fields[k] = v
+			}
+		}
+	}
+
+	return interp.SetVar(varName, obj)
+}
+
+// info classes
+func procInfoClasses(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 1 {
+		return EmptyToken, ErrArgCount(0, len(args)-1)
+	}
+	names := make([]string, 0, len(interp.classes))
+	for name := range interp.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return NewList(NewTokenListString(names)), nil
+}
+
+// info class fields|methods|superclass|ancestors name
+func procInfoClass(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 {
+		return EmptyToken, fmt.Errorf("usage: info class fields|methods|superclass|ancestors name")
+	}
+	cls, err := interp.lookupClass(args[2].String)
+	if err != nil {
+		return EmptyToken, err
+	}
+	switch args[1].String {
+	case "fields":
+		return NewList(NewTokenListString(cls.Fields())), nil
+	case "methods":
+		return NewList(NewTokenListString(cls.Methods())), nil
+	case "superclass":
+		if cls.Super == nil {
+			return EmptyToken, nil
+		}
+		return NewTokenString(cls.Super.Name), nil
+	case "ancestors":
+		var names []string
+		for c := cls.Super; c != nil; c = c.Super {
+			names = append(names, c.Name)
+		}
+		return NewList(NewTokenListString(names)), nil
+	}
+	return EmptyToken, ErrCommand("info class", fmt.Sprintf("unknown subcommand %q", args[1].String))
+}
+
+// info object class|isa obj ?class?
+func procInfoObject(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 3 {
+		return EmptyToken, fmt.Errorf("usage: info object class|isa obj ?class?")
+	}
+	obj, ok := args[2].Data.(*Object)
+	if !ok {
+		if args[1].String == "isa" {
+			return FalseToken, nil
+		}
+		return EmptyToken, fmt.Errorf("%s is not an object", args[2].Summary())
+	}
+	switch args[1].String {
+	case "class":
+		return NewTokenString(obj.Class.Name), nil
+	case "isa":
+		if len(args) != 4 {
+			return EmptyToken, ErrArgCount(3, len(args)-1)
+		}
+		cls, err := interp.lookupClass(args[3].String)
+		if err != nil {
+			return EmptyToken, err
+		}
+		if obj.Class.Is(cls) {
+			return TrueToken, nil
+		}
+		return FalseToken, nil
+	}
+	return EmptyToken, ErrCommand("info object", fmt.Sprintf("unknown subcommand %q", args[1].String))
+}
@@ -0,0 +1,142 @@
+package adz
+
+import (
+	"strings"
+	"testing"
+)
+
+const classShapes = `
+class define Shape {
+	field name shape
+	method describe {} {
+		return "[my name] with area [$self area]"
+	}
+	method area {} { return 0 }
+}
+
+class define Rect {
+	superclass Shape
+	field name rect
+	field w 1
+	field h 1
+	method area {} {
+		* [my w] [my h]
+	}
+	method scale {f} {
+		my w [* [my w] $f]
+		my h [* [my h] $f]
+		return $self
+	}
+}
+
+class define Square {
+	superclass Rect
+	field name square
+	method side {s} {
+		my w $s
+		my h $s
+	}
+}
+`
+
+func TestClass_MethodsAndInheritance(t *testing.T) {
+	interp := NewInterp()
+	if _, err := interp.ExecString(classShapes); err != nil {
+		t.Fatal(err)
+	}
+
+	runScripts(t, interp, []scriptTest{
+		{`[Shape new] describe`, "shape with area 0"},
+		{`set r [Rect new -w 2 -h 3]; $r area`, "6"},
+		{`$r describe`, "rect with area 6"},
+		{`[$r scale 2] area`, "24"},
+		{`$r .w`, "4"},
+		{`$r .w 5; $r area`, "30"},
+		{`set s [Square new]; $s side 3; $s describe`, "square with area 9"},
+		{`Square fields`, "name w h"},
+		{`Square methods`, "area describe scale side"},
+	})
+
+	// instances don't share fields
+	out, _ := interp.ExecString(`set a [Rect new]; set b [Rect new]; $a .w 10; $b .w`)
+	if out.String != "1" {
+		t.Errorf("expected instances to have their own fields, got %q", out.String)
+	}
+
+	for _, script := range []string{
+		`$r nope`,
+		`$r .nope`,
+		`$r scale`,
+		`Rect new -nope 1`,
+		`Rect new -w`,
+		`class define Bad { superclass Missing }`,
+		`class define`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestClass_Info(t *testing.T) {
+	interp := NewInterp()
+	if _, err := interp.ExecString(classShapes); err != nil {
+		t.Fatal(err)
+	}
+
+	runScripts(t, interp, []scriptTest{
+		{`info classes`, "::Rect ::Shape ::Square"},
+		{`info class superclass Square`, "::Rect"},
+		{`info class ancestors Square`, "::Rect ::Shape"},
+		{`info class fields Rect`, "name w h"},
+		{`info class methods Shape`, "area describe"},
+		{`info object class [Square new]`, "::Square"},
+		{`info object isa [Square new] Shape`, "true"},
+		{`info object isa [Shape new] Square`, "false"},
+		{`info object isa notanobject Shape`, "false"},
+	})
+
+	_, err := interp.ExecString(`info bogus`)
+	if err == nil || !strings.Contains(err.Error(), "classes") {
+		t.Errorf("expected error listing subcommands, got %v", err)
+	}
+}
+
+func TestClass_Namespaced(t *testing.T) {
+	interp := NewInterp()
+	_, err := interp.ExecString(`namespace geo {
+		class define Point {
+			field x 0
+			field y 0
+			method sum {} { + [my x] [my y] }
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := interp.ExecString(`[::geo::Point new -x 1 -y 2] sum`)
+	if err != nil || out.String != "3" {
+		t.Errorf("expected 3, got %q, %v", out.String, err)
+	}
+}
+
+func TestAnoint(t *testing.T) {
+	interp := NewInterp()
+	if _, err := interp.ExecString(classShapes); err != nil {
+		t.Fatal(err)
+	}
+	interp.ExecString(`set r {w 4 h 5 other x}`)
+	if _, err := interp.Anoint("r", "Rect"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := interp.ExecString(`$r area`)
+	if err != nil || out.String != "20" {
+		t.Errorf("expected 20, got %q, %v", out.String, err)
+	}
+	if _, err := interp.Anoint("fresh", "Shape"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.Anoint("x", "Nope"); err == nil {
+		t.Errorf("expected error for unknown class")
+	}
+}
@@ -0,0 +1,138 @@
+package adz
+
+func init() {
+	StdLib["closure"] = ProcClosure
+	StdLib["apply"] = ProcApply
+}
+
+func ProcClosure(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		&Argument{
+			Name:    "-vars",
+			Help:    "Variables to copy into the closure. The closure keeps its own copies: changes it makes last from one call to the next, but aren't seen where it was created.",
+			Default: EmptyToken,
+		},
+		&Argument{
+			Name:    "-refs",
+			Help:    "Variables to share, by reference, with the scope the closure is created in.",
+			Default: EmptyToken,
+		},
+		&Argument{
+			Name:   "-frame",
+			Switch: true,
+			Help:   "Share every variable of the scope the closure is created in, by reference, including those created after the closure. New variables the closure sets are still its own.",
+		},
+		ArgHelp("arg", "argument prototype."),
+		ArgHelp("body", "script to execute"),
+	)
+	as.Help = "Creates an anonymous proc, like proc with two arguments, that can use variables of the scope it was created in. Arguments of the closure take precedence over captured variables."
+
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+
+	captured := make(map[string]*Token)
+
+	copies, err := parsedArgs["vars"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["vars"].String)
+	}
+	// copied variables live in a frame of their own, so the closure's
+	// changes persist between calls
+	state := &Frame{localVars: make(map[string]*Token)}
+	for _, name := range copies {
+		val, err := interp.GetVar(name.String)
+		if err != nil {
+			return EmptyToken, err
+		}
+		_, id := identifierParts(name.String)
+		state.localVars[id] = val
+		captured[id] = (&Ref{Name: id, Frame: state}).Token()
+	}
+
+	refs, err := parsedArgs["refs"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["refs"].String)
+	}
+	for _, name := range refs {
+		id, tok, err := interp.closureRef(name.String)
+		if err != nil {
+			return EmptyToken, err
+		}
+		captured[id] = tok
+	}
+
+	var frame *Frame
+	if parsedArgs["frame"].Data.(bool) {
+		frame = interp.Frame
+	}
+
+	name := interp.Monotonic.Next("closure")
+	proc, err := newProc(interp.Frame.localNamespace, name, []*Token{parsedArgs["arg"], parsedArgs["body"]},
+		func(vars map[string]*Token) {
+			for id, tok := range captured {
+				if _, ok := vars[id]; !ok {
+					vars[id] = tok
+				}
+			}
+			if frame == nil {
+				return
+			}
+			for id, tok := range frame.localVars {
+				if _, ok := vars[id]; ok {
+					continue
+				}
+				if _, ok := tok.Data.(*Ref); !ok {
+					tok = (&Ref{Name: id, Frame: frame}).Token()
+				}
+				vars[id] = tok
+			}
+		})
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	tok := NewTokenString(name)
+	tok.Data = Proc(func(pinterp *Interp, pargs []*Token) (*Token, error) {
+		if pinterp.foreign(interp) {
+			return EmptyToken, errForeign(name)
+		}
+		return proc(pinterp, pargs)
+	})
+	return tok, nil
+}
+
+// closureRef returns a token referring to the variable name of the current
+// frame, or for a qualified name, of its namespace, along with the name it
+// has within a closure.
+func (interp *Interp) closureRef(name string) (string, *Token, error) {
+	if isQualified(name) {
+		ref, err := interp.getVarRef(name)
+		if err != nil {
+			return "", nil, err
+		}
+		return ref.Name, ref.Token(), nil
+	}
+	if tok, ok := interp.Frame.localVars[name]; ok {
+		if _, ok := tok.Data.(*Ref); ok {
+			// already a reference, as from import; share it
+			return name, tok, nil
+		}
+	}
+	return name, (&Ref{Name: name, Frame: interp.Frame}).Token(), nil
+}
+
+// apply func ?arg ...? calls func, a list of {args body ?namespace?}, as an
+// anonymous proc with the given args. See Token.AsProc.
+func ProcApply(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	proc, err := args[1].AsProc(interp)
+	if err != nil {
+		return EmptyToken, err
+	}
+	return proc(interp, args[1:])
+}
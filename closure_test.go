@@ -0,0 +1,84 @@
+package adz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClosure(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		// copies are private to the closure but persist between calls
+		{`set n 10; set c [closure -vars n {} {incr n}]; $c; $c; list [$c] $n`, "13 10"},
+		// references write through to the defining scope
+		{`set total 0; set add [closure -refs total {x} {set total [+ $total $x]}]; $add 2; $add 3; return $total`, "5"},
+		// the whole frame, including variables created later
+		{`set f [closure -frame {} {list $a $b}]; set a 1; set b 2; $f`, "1 2"},
+		{`set a 1; set f [closure -frame {} {set a 2; set tmp 3}]; $f; list $a [catch {return $tmp}]`, "2 true"},
+		// arguments shadow captured variables
+		{`set x outer; set f [closure -frame {x} {return $x}]; $f inner`, "inner"},
+		// list::map callbacks can use local state
+		{`proc scale {l k} { list::map $l [closure -vars k {x} {* $x $k}] }; scale {1 2 3} 10`, "10 20 30"},
+		{`proc count {l} { set n 0; list::map $l [closure -refs n {x} {incr n}]; return $n }; count {a b c}`, "3"},
+		// closures outlive the frame they were made in
+		{`proc counter {} { set n 0; closure -refs n {} {incr n} }; set c [counter]; $c; $c`, "2"},
+		{`proc mk {} { set v hi; closure -frame {} {return $v} }; [mk]`, "hi"},
+		// a reference to an imported variable shares the original
+		{`set ::g 1; proc f {} { import -var ::g; set c [closure -refs g {} {set g 5}]; $c }; f; return $::g`, "5"},
+	})
+}
+
+func TestClosure_Name(t *testing.T) {
+	interp := NewInterp()
+	out, err := interp.ExecString(`closure {} {}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String, "closure#") {
+		t.Errorf("expected a closure#N name, got %q", out.String)
+	}
+	if _, err := interp.ExecString(`closure -vars nosuchvar {} {}`); err == nil {
+		t.Error("expected error capturing a missing variable")
+	}
+}
+
+func TestApply(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`apply {{x y} {+ $x $y}} 1 2`, "3"},
+		{`apply {{} {return early; return late}}`, "early"},
+		{`apply {{{x 5}} {return $x}}`, "5"},
+		{`apply {args {list::len $args}} a b c`, "3"},
+		{`namespace ::ns { set v nsvar }; apply {{} {return $::ns::v} ::ns}`, "nsvar"},
+		{`namespace ::ns { proc helper {} { return helped } }; apply {{} {helper} ns}`, "helped"},
+		{`apply {{} {namespace} ::ns}`, "::ns"},
+		{`set f {{x} {* $x 2}}; apply $f 2; apply $f 4`, "8"},
+		{`set f [closure {x} {* $x 3}]; apply $f 2`, "6"},
+		{`apply [list {x} {return $x}] listed`, "listed"},
+	})
+
+	interp := NewInterp()
+	for _, script := range []string{
+		`apply`,
+		`apply {{x} {return $x}}`,
+		`apply {just-one}`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestAsProc_Cached(t *testing.T) {
+	interp := NewInterp()
+	tok := NewTokenString(`{x} {return $x}`)
+	proc, err := tok.AsProc(interp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := tok.Data.(Proc); !ok {
+		t.Fatalf("expected proc to be cached, got %T", tok.Data)
+	}
+	out, err := proc(interp, []*Token{tok, NewTokenString("v")})
+	if err != nil || out.String != "v" {
+		t.Errorf("expected v, got %q, %v", out.String, err)
+	}
+}
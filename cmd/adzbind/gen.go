@@ -0,0 +1,556 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"math"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const adzPath = "github.com/sparques/adz"
+
+// generator accumulates the generated source for one package.
+type generator struct {
+	pkg     *packages.Package
+	varName string
+
+	// imports maps import path to the name it is imported as; names is the reverse.
+	imports map[string]string
+	names   map[string]string
+
+	// docs maps functions and methods to their declarations so doc
+	// comments can become help text.
+	docs map[types.Object]*ast.FuncDecl
+
+	entries []entry
+	body    bytes.Buffer
+	tmp     int
+}
+
+// entry is one element of the generated map.
+type entry struct {
+	procName, ident string
+}
+
+// param describes how one Go parameter is filled in.
+type param struct {
+	name string
+	typ  types.Type
+	// source is where the value comes from instead of a bound arg, e.g.
+	// "interp" for an *adz.Interp parameter; empty for regular args.
+	source string
+}
+
+func generate(pkg *packages.Package, pkgName, varName string) ([]byte, error) {
+	g := &generator{
+		pkg:     pkg,
+		varName: varName,
+		imports: map[string]string{},
+		names:   map[string]string{},
+		docs:    map[types.Object]*ast.FuncDecl{},
+	}
+	// reserve these names first; fmt is dropped again if nothing uses it
+	g.importName(adzPath, "adz")
+	g.importName("fmt", "fmt")
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				if obj := pkg.TypesInfo.Defs[fd.Name]; obj != nil {
+					g.docs[obj] = fd
+				}
+			}
+		}
+	}
+
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Func:
+			if obj.Exported() {
+				g.function(obj)
+			}
+		case *types.TypeName:
+			if obj.Exported() && !obj.IsAlias() {
+				g.methods(obj)
+			}
+		}
+	}
+
+	if !bytes.Contains(g.body.Bytes(), []byte("fmt.")) {
+		delete(g.imports, "fmt")
+	}
+
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "// Code generated by adzbind %s; DO NOT EDIT.\n\n", pkg.PkgPath)
+	fmt.Fprintf(src, "package %s\n\n", pkgName)
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	src.WriteString("import (\n")
+	for _, path := range paths {
+		if g.imports[path] == pathpkg.Base(path) {
+			fmt.Fprintf(src, "\t%q\n", path)
+			continue
+		}
+		fmt.Fprintf(src, "\t%s %q\n", g.imports[path], path)
+	}
+	src.WriteString(")\n\n")
+
+	fmt.Fprintf(src, "// %s holds a proc for each exported function and method of %s.\n", varName, pkg.PkgPath)
+	fmt.Fprintf(src, "var %s = map[string]adz.Proc{\n", varName)
+	for _, e := range g.entries {
+		fmt.Fprintf(src, "\t%q: %s,\n", e.procName, e.ident)
+	}
+	src.WriteString("}\n\n")
+	src.Write(g.body.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return src.Bytes(), fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+// importName returns the name the package at path is imported as, adding it
+// to the imports if needed and renaming it if its name is taken.
+func (g *generator) importName(path, name string) string {
+	if n, ok := g.imports[path]; ok {
+		return n
+	}
+	base, n := name, name
+	for i := 2; g.names[n] != ""; i++ {
+		n = fmt.Sprintf("%s%d", base, i)
+	}
+	g.imports[path] = n
+	g.names[n] = path
+	return n
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+	return g.importName(p.Path(), p.Name())
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) function(fn *types.Func) {
+	sig := fn.Type().(*types.Signature)
+	if sig.TypeParams().Len() > 0 || !nameable(sig) {
+		return
+	}
+	call := g.qualifier(fn.Pkg()) + "." + fn.Name()
+	g.proc(fn.Name(), fn, nil, call, sig)
+}
+
+func (g *generator) methods(tn *types.TypeName) {
+	named, ok := tn.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return
+	}
+	recvType := types.Type(types.NewPointer(named))
+	if types.IsInterface(named) {
+		recvType = named
+	}
+	mset := types.NewMethodSet(recvType)
+	for i := 0; i < mset.Len(); i++ {
+		fn := mset.At(i).Obj().(*types.Func)
+		sig := fn.Type().(*types.Signature)
+		if !fn.Exported() || !nameable(sig) {
+			continue
+		}
+		g.proc(tn.Name()+"."+fn.Name(), fn, named, "recv."+fn.Name(), sig)
+	}
+}
+
+// proc writes the ArgSet and Proc for a function or, if recv is not nil, a
+// method of recv.
+func (g *generator) proc(procName string, fn *types.Func, recv *types.Named, call string, sig *types.Signature) {
+	ident := unexportedName(g.varName) + "_" + strings.ReplaceAll(procName, ".", "_")
+	argsIdent := ident + "Args"
+	g.entries = append(g.entries, entry{procName: procName, ident: ident})
+
+	params := g.params(sig)
+
+	// ArgSet
+	w := &g.body
+	fmt.Fprintf(w, "var %s = &adz.ArgSet{\n", argsIdent)
+	fmt.Fprintf(w, "\tCmd: %q,\n", procName)
+	if doc := g.doc(fn); doc != "" {
+		fmt.Fprintf(w, "\tHelp: %s,\n", strconv.Quote(doc))
+	}
+	fmt.Fprintf(w, "\tLazy: true,\n")
+	fmt.Fprintf(w, "\tArgGroups: []*adz.ArgGroup{adz.NewArgGroup(\n")
+	if recv != nil {
+		fmt.Fprintf(w, "\t\tadz.ArgHelp(%q, %q),\n", "recv", "receiver: "+g.typeString(recv))
+	}
+	for _, p := range params {
+		if p.source == "" {
+			fmt.Fprintf(w, "\t\tadz.ArgHelp(%q, %q),\n", p.name, g.typeString(p.typ))
+		}
+	}
+	fmt.Fprintf(w, "\t)},\n}\n\n")
+
+	// Proc
+	fmt.Fprintf(w, "func %s(interp *adz.Interp, args []*adz.Token) (*adz.Token, error) {\n", ident)
+	boundIdent := "_"
+	if recv != nil {
+		boundIdent = "bound"
+	}
+	for _, p := range params {
+		if p.source == "" {
+			boundIdent = "bound"
+		}
+	}
+	fmt.Fprintf(w, "\t%s, err := %s.BindPosOnly(interp, args)\n", boundIdent, argsIdent)
+	fmt.Fprintf(w, "\tif err != nil {\n\t\t%s.ShowUsage(interp.Stderr)\n\t\treturn adz.EmptyToken, err\n\t}\n", argsIdent)
+
+	if recv != nil {
+		// interfaces and structs have to be in Data already; anything
+		// else can be converted from the token like any other arg.
+		switch recv.Underlying().(type) {
+		case *types.Interface:
+			fmt.Fprintf(w, "\trecv, err := adz.TokenData[%s](bound[\"recv\"])\n", g.typeString(recv))
+			fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, fmt.Errorf(\"argument {recv}: %%w\", err)\n\t}\n")
+		case *types.Struct:
+			fmt.Fprintf(w, "\trecv, err := adz.TokenDataPtr[%s](bound[\"recv\"])\n", g.typeString(recv))
+			fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, fmt.Errorf(\"argument {recv}: %%w\", err)\n\t}\n")
+		default:
+			fmt.Fprintf(w, "\tvar recvVal %s\n", g.typeString(recv))
+			g.convert(w, "recvVal", `bound["recv"]`, recv, argErrCtx("recv"))
+			fmt.Fprintf(w, "\trecv := &recvVal\n")
+		}
+	}
+
+	callArgs := make([]string, len(params))
+	for i, p := range params {
+		dst := fmt.Sprintf("p%d", i)
+		callArgs[i] = dst
+		switch {
+		case p.source != "":
+			callArgs[i] = p.source
+			continue
+		case sig.Variadic() && i == len(params)-1:
+			fmt.Fprintf(w, "\tvar %s %s\n", dst, g.typeString(p.typ))
+			g.convertSlice(w, dst, `bound["args"]`, p.typ.(*types.Slice), argErrCtx(p.name))
+			callArgs[i] += "..."
+			continue
+		}
+		fmt.Fprintf(w, "\tvar %s %s\n", dst, g.typeString(p.typ))
+		g.convert(w, dst, fmt.Sprintf("bound[%q]", p.name), p.typ, argErrCtx(p.name))
+	}
+
+	g.results(w, sig.Results(), call+"("+strings.Join(callArgs, ", ")+")")
+	fmt.Fprintf(w, "}\n\n")
+}
+
+// params names fn's parameters, noting those that are filled in from the
+// interpreter rather than from args.
+func (g *generator) params(sig *types.Signature) []param {
+	params := make([]param, sig.Params().Len())
+	taken := map[string]bool{"recv": true}
+	for i := range params {
+		v := sig.Params().At(i)
+		p := param{name: v.Name(), typ: v.Type()}
+		switch {
+		case sig.Variadic() && i == len(params)-1:
+			p.name = "args"
+		case p.name == "" || p.name == "_" || p.name == "args" || taken[p.name]:
+			p.name = fmt.Sprintf("arg%d", i+1)
+		}
+		taken[p.name] = true
+
+		switch {
+		case isNamed(p.typ, "context", "Context"):
+			p.source = g.qualifier(p.typ.(*types.Named).Obj().Pkg()) + ".Background()"
+		case isPointerTo(p.typ, adzPath, "Interp"):
+			p.source = "interp"
+		}
+		params[i] = p
+	}
+	return params
+}
+
+// errCtx describes how generated code wraps a conversion error: format is a
+// fmt prefix for the message and args are the Go expressions it refers to.
+type errCtx struct {
+	format string
+	args   []string
+}
+
+func argErrCtx(name string) errCtx {
+	return errCtx{format: "argument {" + name + "}: "}
+}
+
+// elem returns the context for an element of a list, indexed by idx.
+func (ec errCtx) elem(idx string) errCtx {
+	return errCtx{
+		format: ec.format + "element %d: ",
+		args:   append(append([]string{}, ec.args...), idx),
+	}
+}
+
+// expr is the generated expression wrapping err.
+func (ec errCtx) expr() string {
+	args := append(append([]string{}, ec.args...), "err")
+	return fmt.Sprintf("fmt.Errorf(%q, %s)", ec.format+"%w", strings.Join(args, ", "))
+}
+
+// convert writes code assigning the token expression src, converted to type
+// t, to dst. On failure, the generated code returns an error wrapped per ec.
+func (g *generator) convert(w *bytes.Buffer, dst, src string, t types.Type, ec errCtx) {
+	if isPointerTo(t, adzPath, "Token") {
+		fmt.Fprintf(w, "\t%s = %s\n", dst, src)
+		return
+	}
+
+	tmp := g.temp("v")
+	typ := g.typeString(t)
+	checkErr := func() {
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, %s\n\t}\n", ec.expr())
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		var method, native string
+		switch {
+		case info&types.IsString != 0:
+			fmt.Fprintf(w, "\t%s = %s\n", dst, convertExpr(typ, "string", src+".String"))
+			return
+		case info&types.IsBoolean != 0:
+			method, native = "AsBool", "bool"
+		case info&types.IsInteger != 0:
+			method, native = "AsInt", "int"
+		case info&types.IsFloat != 0:
+			method, native = "AsFloat", "float64"
+		}
+		if method != "" {
+			fmt.Fprintf(w, "\t%s, err := %s.%s()\n", tmp, src, method)
+			if lo, hi, ok := intRange(u.Kind()); ok {
+				fmt.Fprintf(w, "\tif err == nil && (int64(%s) < %d || int64(%s) > %d) {\n", tmp, lo, tmp, hi)
+				fmt.Fprintf(w, "\t\terr = adz.ErrExpectedInt(fmt.Sprintf(\"%%d, out of range for %s\", %s))\n\t}\n", u.Name(), tmp)
+			} else if info&types.IsUnsigned != 0 {
+				fmt.Fprintf(w, "\tif err == nil && %s < 0 {\n", tmp)
+				fmt.Fprintf(w, "\t\terr = adz.ErrExpectedInt(fmt.Sprintf(\"%%d, out of range for %s\", %s))\n\t}\n", u.Name(), tmp)
+			}
+			checkErr()
+			fmt.Fprintf(w, "\t%s = %s\n", dst, convertExpr(typ, native, tmp))
+			return
+		}
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			fmt.Fprintf(w, "\t%s = %s(%s.String)\n", dst, typ, src)
+			return
+		}
+		if _, ok := t.(*types.Named); !ok {
+			// scoped, as err may not have been declared yet
+			fmt.Fprintf(w, "\t{\n")
+			g.convertSlice(w, dst, src, u, ec)
+			fmt.Fprintf(w, "\t}\n")
+			return
+		}
+	case *types.Struct:
+		// a GoObject holds a *T, so accept that as well as a T
+		if _, ok := t.(*types.Named); ok {
+			fmt.Fprintf(w, "\t%s, err := adz.TokenDataPtr[%s](%s)\n", tmp, typ, src)
+			checkErr()
+			fmt.Fprintf(w, "\t%s = *%s\n", dst, tmp)
+			return
+		}
+	}
+
+	// everything else has to already be sitting in the token's Data
+	if ptr, ok := t.(*types.Pointer); ok {
+		if _, ok := ptr.Elem().Underlying().(*types.Struct); ok {
+			fmt.Fprintf(w, "\t%s, err := adz.TokenDataPtr[%s](%s)\n", tmp, g.typeString(ptr.Elem()), src)
+			checkErr()
+			fmt.Fprintf(w, "\t%s = %s\n", dst, tmp)
+			return
+		}
+	}
+	fmt.Fprintf(w, "\t%s, err := adz.TokenData[%s](%s)\n", tmp, typ, src)
+	checkErr()
+	fmt.Fprintf(w, "\t%s = %s\n", dst, tmp)
+}
+
+// intRange returns the bounds of the integer kind k, if narrower than the
+// int AsInt returns on every platform. Unsigned kinds at least as wide as
+// int only need checking for negatives.
+func intRange(k types.BasicKind) (lo, hi int64, ok bool) {
+	switch k {
+	case types.Int8:
+		return math.MinInt8, math.MaxInt8, true
+	case types.Int16:
+		return math.MinInt16, math.MaxInt16, true
+	case types.Int32:
+		return math.MinInt32, math.MaxInt32, true
+	case types.Uint8:
+		return 0, math.MaxUint8, true
+	case types.Uint16:
+		return 0, math.MaxUint16, true
+	case types.Uint32:
+		return 0, math.MaxUint32, true
+	}
+	return 0, 0, false
+}
+
+// convertSlice writes code converting the list token src element by element.
+func (g *generator) convertSlice(w *bytes.Buffer, dst, src string, t *types.Slice, ec errCtx) {
+	list, idx := g.temp("l"), g.temp("i")
+	fmt.Fprintf(w, "\t%s, err := %s.AsList()\n", list, src)
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, %s\n\t}\n", ec.expr())
+	fmt.Fprintf(w, "\t%s = make(%s, len(%s))\n", dst, g.typeString(t), list)
+	fmt.Fprintf(w, "\tfor %s := range %s {\n", idx, list)
+	g.convert(w, dst+"["+idx+"]", list+"["+idx+"]", t.Elem(), ec.elem(idx))
+	fmt.Fprintf(w, "\t}\n")
+}
+
+// temp returns a new, unique identifier starting with prefix.
+func (g *generator) temp(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+// convertExpr converts expr, of type native, to typ, eliding the conversion
+// when they are the same.
+func convertExpr(typ, native, expr string) string {
+	if typ == native {
+		return expr
+	}
+	return typ + "(" + expr + ")"
+}
+
+// results writes the call and maps its results onto (*adz.Token, error).
+func (g *generator) results(w *bytes.Buffer, res *types.Tuple, call string) {
+	n := res.Len()
+	hasErr := n > 0 && types.Identical(res.At(n-1).Type(), types.Universe.Lookup("error").Type())
+	if hasErr {
+		n--
+	}
+
+	vars := make([]string, n)
+	for i := range vars {
+		vars[i] = fmt.Sprintf("r%d", i)
+	}
+
+	switch {
+	case n == 0 && !hasErr:
+		fmt.Fprintf(w, "\t%s\n\treturn adz.EmptyToken, nil\n", call)
+		return
+	case n == 0:
+		fmt.Fprintf(w, "\tif err := %s; err != nil {\n\t\treturn adz.EmptyToken, err\n\t}\n", call)
+		fmt.Fprintf(w, "\treturn adz.EmptyToken, nil\n")
+		return
+	case hasErr:
+		fmt.Fprintf(w, "\t%s, err := %s\n", strings.Join(vars, ", "), call)
+		fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, err\n\t}\n")
+	default:
+		fmt.Fprintf(w, "\t%s := %s\n", strings.Join(vars, ", "), call)
+	}
+
+	toks := make([]string, n)
+	for i := range vars {
+		toks[i] = g.resultToken(w, vars[i], res.At(i).Type())
+	}
+	if n == 1 {
+		fmt.Fprintf(w, "\treturn %s, nil\n", toks[0])
+		return
+	}
+	fmt.Fprintf(w, "\treturn adz.NewList([]*adz.Token{%s}), nil\n", strings.Join(toks, ", "))
+}
+
+// resultToken returns an expression for the result v, of type t, as a
+// token. Slices and maps are marshaled into lists, writing code to do so
+// first; anything else keeps its value in Data.
+func (g *generator) resultToken(w *bytes.Buffer, v string, t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "adz.NewTokenBytes(" + v + ")"
+		}
+	case *types.Array, *types.Map:
+	default:
+		return "adz.NewToken(" + v + ")"
+	}
+	tok := g.temp("t")
+	fmt.Fprintf(w, "\t%s, err := adz.Marshal(%s)\n", tok, v)
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn adz.EmptyToken, err\n\t}\n")
+	return tok
+}
+
+// doc returns fn's doc comment collapsed onto one line.
+func (g *generator) doc(fn *types.Func) string {
+	fd, ok := g.docs[fn]
+	if !ok || fd.Doc == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(fd.Doc.Text()), " ")
+}
+
+// nameable reports whether every type in sig can be written from another
+// package, i.e. it does not mention unexported types.
+func nameable(sig *types.Signature) bool {
+	for _, tup := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tup.Len(); i++ {
+			if !typeNameable(tup.At(i).Type()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func typeNameable(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() != nil && !t.Obj().Exported() {
+			return false
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if !typeNameable(t.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	case *types.Pointer:
+		return typeNameable(t.Elem())
+	case *types.Slice:
+		return typeNameable(t.Elem())
+	case *types.Array:
+		return typeNameable(t.Elem())
+	case *types.Map:
+		return typeNameable(t.Key()) && typeNameable(t.Elem())
+	case *types.Chan:
+		return typeNameable(t.Elem())
+	case *types.Signature:
+		return nameable(t)
+	case *types.Basic:
+		return t.Kind() != types.UnsafePointer
+	case *types.TypeParam:
+		return false
+	}
+	return true
+}
+
+func isNamed(t types.Type, path, name string) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == path && named.Obj().Name() == name
+}
+
+func isPointerTo(t types.Type, path, name string) bool {
+	ptr, ok := t.(*types.Pointer)
+	return ok && isNamed(ptr.Elem(), path, name)
+}
@@ -0,0 +1,111 @@
+// Command adzbind generates adz bindings for a Go package.
+//
+// It loads the package named by pattern and writes a Go source file holding a
+// map[string]adz.Proc, suitable for Interp.LoadProcs, with a proc for every
+// exported function and for every exported method of every exported type.
+// Methods are named Type.Method and take the receiver as their first
+// argument.
+//
+// The generated procs convert their arguments with static code rather than
+// reflection, and each one has an ArgSet built from the Go parameter names
+// with the doc comment as its help text. Integer args out of range for their
+// parameter's type are an error rather than wrapping. Slice and map results
+// are returned as lists, as from adz.Marshal.
+//
+// Usage:
+//
+//	adzbind [-o file] [-package name] [-var name] pattern
+//
+// For example, to bind strconv:
+//
+//	//go:generate adzbind -o strconv_adz.go -var StrconvProcs strconv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	var (
+		out     = flag.String("o", "", "output file; default is stdout")
+		pkgName = flag.String("package", "main", "package name of the generated file")
+		varName = flag.String("var", "", "name of the generated map; default is <Pkg>Procs")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: adzbind [flags] pattern\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, err := load(flag.Arg(0), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "adzbind: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *varName == "" {
+		*varName = exportedName(pkg.Name) + "Procs"
+	}
+
+	src, err := generate(pkg, *pkgName, *varName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "adzbind: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "adzbind: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// load loads the single package matched by pattern, resolved relative to dir.
+func load(pattern, dir string) (*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: expected exactly one package, got %d", pattern, len(pkgs))
+	}
+	if len(pkgs[0].Errors) > 0 {
+		return nil, pkgs[0].Errors[0]
+	}
+	return pkgs[0], nil
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func unexportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return strings.ReplaceAll(string(r), ".", "_")
+}
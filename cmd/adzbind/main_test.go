@@ -0,0 +1,137 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_Sample(t *testing.T) {
+	pkg, err := load("./testdata/sample", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	src, err := generate(pkg, "sample_test", "SampleProcs")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+
+	out := string(src)
+	for _, want := range []string{
+		`"Add":`,
+		`"Join":`,
+		`"Scale":`,
+		`"Div":`,
+		`"Depth":`,
+		`"Celsius.Fahrenheit":`,
+		`"Counter.Incr":`,
+		`"NewCounter":`,
+		`adz.TokenDataPtr[sample.Counter](bound["recv"])`,
+		`context.Background()`,
+		`fmt.Errorf("argument {xs}: element %d: %w", `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code is missing %s", want)
+		}
+	}
+
+	for _, unwanted := range []string{"hidden", "Hide", "unexported"} {
+		if strings.Contains(out, `"`+unwanted) {
+			t.Errorf("generated code binds %s", unwanted)
+		}
+	}
+}
+
+// TestGenerate_Run builds the bindings for testdata/sample into a program and
+// runs scripts calling them.
+func TestGenerate_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	pkg, err := load("./testdata/sample", "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	src, err := generate(pkg, "main", "SampleProcs")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// inside the module, so that it builds against this adz
+	dir, err := os.MkdirTemp("testdata", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "sample_adz.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sparques/adz"
+)
+
+func main() {
+	interp := adz.NewInterp()
+	interp.LoadProcs("sample", SampleProcs)
+	for _, script := range os.Args[1:] {
+		out, err := interp.ExecString(script)
+		if err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		fmt.Println(out.String)
+	}
+}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ script, want string }{
+		{`sample::Add 2 3`, "5"},
+		{`sample::Join - a b c`, "a-b-c"},
+		{`sample::Scale {1 2 3} 2`, "2 4 6"},
+		{`sample::Letters abca`, "a 2 b 1 c 1"},
+		{`sample::Shift 3 2`, "12"},
+		{`sample::Shift 300 1`, "error: sample::Shift: argument {b}: expected integer, got 300, out of range for int8"},
+		{`sample::Shift 1 -1`, "error: sample::Shift: argument {n}: expected integer, got -1, out of range for uint8"},
+		{`sample::Counter.Incr [sample::NewCounter 1] 2`, "3"},
+	}
+	args := []string{"run", "./" + filepath.ToSlash(dir)}
+	for _, tc := range tests {
+		args = append(args, tc.script)
+	}
+	out, err := exec.Command(gobin, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != len(tests) {
+		t.Fatalf("expected %d lines of output, got:\n%s", len(tests), out)
+	}
+	for i, tc := range tests {
+		if lines[i] != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.want, lines[i])
+		}
+	}
+}
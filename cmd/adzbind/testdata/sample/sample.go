@@ -0,0 +1,75 @@
+// Package sample is bound by the adzbind tests.
+package sample
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sparques/adz"
+)
+
+// Add returns the sum of a and b.
+func Add(a, b int) int { return a + b }
+
+// Join joins elems with sep.
+func Join(sep string, elems ...string) string { return strings.Join(elems, sep) }
+
+// Scale multiplies each of xs by factor.
+func Scale(xs []float64, factor float64) []float64 {
+	out := make([]float64, len(xs))
+	for i := range xs {
+		out[i] = xs[i] * factor
+	}
+	return out
+}
+
+// Div divides a by b.
+func Div(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("divide by zero")
+	}
+	return a / b, nil
+}
+
+// Shift shifts b left by n bits.
+func Shift(b int8, n uint8) int8 { return b << n }
+
+// Letters counts each letter of s.
+func Letters(s string) map[string]int {
+	counts := map[string]int{}
+	for _, r := range s {
+		counts[string(r)]++
+	}
+	return counts
+}
+
+// Depth reports the interpreter's call depth.
+func Depth(ctx context.Context, interp *adz.Interp) int { return interp.CallDepth() }
+
+// Celsius is a temperature.
+type Celsius float64
+
+// Fahrenheit converts c to Fahrenheit.
+func (c Celsius) Fahrenheit() float64 { return float64(c)*9/5 + 32 }
+
+// Counter counts.
+type Counter struct {
+	N int
+}
+
+// NewCounter returns a Counter starting at n.
+func NewCounter(n int) *Counter { return &Counter{N: n} }
+
+// Incr adds by to the counter and returns the new count.
+func (c *Counter) Incr(by int) int {
+	c.N += by
+	return c.N
+}
+
+func unexported() {}
+
+type hidden struct{}
+
+// Hide can't be bound; its signature mentions an unexported type.
+func Hide(h hidden) {}
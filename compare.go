@@ -0,0 +1,317 @@
+package adz
+
+import (
+	"cmp"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Comparer is implemented by Token Data that knows how to compare itself
+// with other values. Compare returns a negative number, zero or a positive
+// number as the receiver is less than, equal to or greater than other; ok is
+// false if the receiver can't be ordered against other, in which case the
+// usual rules apply. Equal reports whether the receiver and other are the
+// same value.
+type Comparer interface {
+	Compare(other *Token) (c int, ok bool)
+	Equal(other *Token) bool
+}
+
+// CompareMode selects the rules a Collation compares values by.
+type CompareMode int
+
+const (
+	// CompareAuto compares by value: Data implementing Comparer decides for
+	// itself, numbers and booleans compare numerically, with true and false
+	// as 1 and 0, lists of other than one element element by element, dicts
+	// (for equality) regardless of key order, and anything else as strings.
+	// Whether a value is a number, boolean or list depends only on its
+	// string, never on what's cached in Data.
+	CompareAuto CompareMode = iota
+	// CompareASCII compares strings byte by byte.
+	CompareASCII
+	// CompareNumeric compares numbers numerically. Non-numbers sort after
+	// numbers, by string.
+	CompareNumeric
+	// CompareDictionary compares strings ignoring case, except to break
+	// ties, and with runs of digits compared as integers, so a2 < a10.
+	CompareDictionary
+)
+
+// Collation compares tokens. The zero value compares with CompareAuto.
+type Collation struct {
+	Mode   CompareMode
+	NoCase bool
+}
+
+// Compare returns a negative number, zero or a positive number as a is less
+// than, equal to or greater than b.
+func (c Collation) Compare(a, b *Token) int {
+	switch c.Mode {
+	case CompareASCII:
+		return c.compareStrings(a.String, b.String)
+	case CompareDictionary:
+		return dictionaryCompare(a.String, b.String, c.NoCase)
+	case CompareNumeric:
+		x, errX := numericFromToken(a)
+		y, errY := numericFromToken(b)
+		switch {
+		case errX == nil && errY == nil:
+			return compareNumbers(x, y)
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		}
+		return c.compareStrings(a.String, b.String)
+	}
+
+	if cmpr, ok := a.Data.(Comparer); ok {
+		if r, ok := cmpr.Compare(b); ok {
+			return r
+		}
+	}
+	if cmpr, ok := b.Data.(Comparer); ok {
+		if r, ok := cmpr.Compare(a); ok {
+			return -r
+		}
+	}
+	if x, ok := scalarFromToken(a); ok {
+		if y, ok := scalarFromToken(b); ok {
+			return compareNumbers(x, y)
+		}
+	}
+	if isCompound(a) || isCompound(b) {
+		return c.compareLists(a, b)
+	}
+	return c.compareStrings(a.String, b.String)
+}
+
+// Equal reports whether a and b are equal under c.
+func (c Collation) Equal(a, b *Token) bool {
+	if c.Mode == CompareAuto {
+		if eq, ok := a.Data.(Comparer); ok {
+			return eq.Equal(b)
+		}
+		if eq, ok := b.Data.(Comparer); ok {
+			return eq.Equal(a)
+		}
+		if eq, ok := a.Data.(Equivalence); ok {
+			return eq.Equal(b)
+		}
+		_, aDict := a.Data.(*Dict)
+		_, bDict := b.Data.(*Dict)
+		if aDict || bDict {
+			return c.dictsEqual(a, b)
+		}
+	}
+	return c.Compare(a, b) == 0
+}
+
+// Check returns an error if any of toks can't be compared under c; that is,
+// if c is numeric and one isn't a number.
+func (c Collation) Check(toks ...*Token) error {
+	if c.Mode != CompareNumeric {
+		return nil
+	}
+	for _, tok := range toks {
+		if _, err := numericFromToken(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c Collation) compareStrings(a, b string) int {
+	if c.NoCase {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func (c Collation) compareLists(a, b *Token) int {
+	x, errX := a.AsList()
+	y, errY := b.AsList()
+	if errX != nil || errY != nil {
+		return c.compareStrings(a.String, b.String)
+	}
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if r := c.Compare(x[i], y[i]); r != 0 {
+			return r
+		}
+	}
+	return cmp.Compare(len(x), len(y))
+}
+
+func (c Collation) dictsEqual(a, b *Token) bool {
+	x, errX := a.AsDict()
+	y, errY := b.AsDict()
+	if errX != nil || errY != nil || x.Len() != y.Len() {
+		return false
+	}
+	for _, k := range x.Keys() {
+		v, ok := y.Get(k)
+		if !ok || !c.Equal(x.vals[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCompound reports whether tok is a list of other than one element, which
+// compares element by element.
+func isCompound(tok *Token) bool {
+	if !strings.ContainsAny(tok.String, " \t\r\n") {
+		return false
+	}
+	list, err := tok.AsList()
+	return err == nil && len(list) != 1
+}
+
+func compareNumbers(x, y numericValue) int {
+	if x.isFloat || y.isFloat {
+		return cmp.Compare(x.Float64(), y.Float64())
+	}
+	return compareInts(x, y)
+}
+
+// scalarFromToken returns tok as a number, with the booleans true, on and
+// yes as 1, and false, off and no as 0, so that comparing numbers and
+// booleans is transitive.
+func scalarFromToken(tok *Token) (numericValue, bool) {
+	if n, err := numericFromToken(tok); err == nil {
+		return n, true
+	}
+	switch strings.ToLower(tok.String) {
+	case "true", "on", "yes":
+		return intValue(1), true
+	case "false", "off", "no":
+		return intValue(0), true
+	}
+	return numericValue{}, false
+}
+
+// dictionaryCompare compares a and b for CompareDictionary. Unless noCase
+// is set, strings differing only in case are ordered by their first
+// differing letter, uppercase first.
+func dictionaryCompare(a, b string, noCase bool) int {
+	tie := 0
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			i, j := digitRun(a), digitRun(b)
+			x, y := strings.TrimLeft(a[:i], "0"), strings.TrimLeft(b[:j], "0")
+			if len(x) != len(y) {
+				return cmp.Compare(len(x), len(y))
+			}
+			if r := strings.Compare(x, y); r != 0 {
+				return r
+			}
+			a, b = a[i:], b[j:]
+			continue
+		}
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return cmp.Compare(la, lb)
+		}
+		if tie == 0 {
+			tie = cmp.Compare(ra, rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	if r := cmp.Compare(len(a), len(b)); r != 0 || noCase {
+		return r
+	}
+	return tie
+}
+
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// parseCompareFlags removes the comparison options -ascii, -dictionary,
+// -numeric and -nocase from the start of args[1:], stopping at the first
+// other argument or after --. It returns the resulting Collation and args
+// without the options.
+func parseCompareFlags(args []*Token) (Collation, []*Token) {
+	var c Collation
+	i := 1
+loop:
+	for ; i < len(args); i++ {
+		switch args[i].String {
+		case "-ascii":
+			c.Mode = CompareASCII
+		case "-dictionary":
+			c.Mode = CompareDictionary
+		case "-numeric":
+			c.Mode = CompareNumeric
+		case "-nocase":
+			c.NoCase = true
+		case "--":
+			i++
+			break loop
+		default:
+			break loop
+		}
+	}
+	return c, append([]*Token{args[0]}, args[i:]...)
+}
+
+// Compare returns a negative number, zero or a positive number as tok is less
+// than, equal to or greater than other, using CompareAuto.
+func (tok *Token) Compare(other *Token) int {
+	return Collation{}.Compare(tok, other)
+}
+
+// Equal reports whether tok and other are equal, using CompareAuto.
+func (tok *Token) Equal(other *Token) bool {
+	return Collation{}.Equal(tok, other)
+}
+
+// Compare orders g against another GoObject of the same type if the wrapped
+// type has a method Compare(T) int, as with time.Time.
+func (g *GoObject) Compare(other *Token) (int, bool) {
+	o, ok := other.Data.(*GoObject)
+	if !ok || o.typ != g.typ {
+		return 0, false
+	}
+	m := g.ptr.MethodByName("Compare")
+	if !m.IsValid() || m.Type().NumIn() != 1 || m.Type().NumOut() != 1 ||
+		m.Type().Out(0).Kind() != reflect.Int {
+		return 0, false
+	}
+	arg := o.ptr
+	if m.Type().In(0) == g.typ {
+		arg = arg.Elem()
+	} else if m.Type().In(0) != o.ptr.Type() {
+		return 0, false
+	}
+	return int(m.Call([]reflect.Value{arg})[0].Int()), true
+}
+
+// Equal reports whether other wraps an equal Go value: by the wrapped type's
+// Equal(T) bool method if it has one, as with time.Time, or by
+// reflect.DeepEqual.
+func (g *GoObject) Equal(other *Token) bool {
+	o, ok := other.Data.(*GoObject)
+	if !ok || o.typ != g.typ {
+		return false
+	}
+	m := g.ptr.MethodByName("Equal")
+	if m.IsValid() && m.Type().NumIn() == 1 && m.Type().NumOut() == 1 &&
+		m.Type().Out(0).Kind() == reflect.Bool {
+		switch m.Type().In(0) {
+		case g.typ:
+			return m.Call([]reflect.Value{o.ptr.Elem()})[0].Bool()
+		case o.ptr.Type():
+			return m.Call([]reflect.Value{o.ptr})[0].Bool()
+		}
+	}
+	return reflect.DeepEqual(g.ptr.Elem().Interface(), o.ptr.Elem().Interface())
+}
@@ -0,0 +1,131 @@
+package adz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCollation(t *testing.T) {
+	for _, tc := range []struct {
+		coll Collation
+		a, b string
+		want int
+	}{
+		{Collation{}, "1", "1.0", 0},
+		{Collation{}, "2", "10", -1},
+		{Collation{}, "0x10", "16", 0},
+		{Collation{}, "true", "on", 0},
+		{Collation{}, "no", "yes", -1},
+		{Collation{}, "true", "1", 0},
+		{Collation{}, "apple", "banana", -1},
+		{Collation{}, "B", "a", -1},
+		{Collation{NoCase: true}, "B", "a", 1},
+		{Collation{Mode: CompareASCII}, "1", "1.0", -1},
+		{Collation{Mode: CompareASCII}, "10", "9", -1},
+		{Collation{Mode: CompareNumeric}, "10", "9", 1},
+		{Collation{Mode: CompareNumeric}, "abc", "9", 1},
+		{Collation{Mode: CompareDictionary}, "a10", "a2", 1},
+		{Collation{Mode: CompareDictionary}, "Abc", "abd", -1},
+		{Collation{Mode: CompareDictionary}, "ABC", "abc", -1},
+		{Collation{Mode: CompareDictionary, NoCase: true}, "ABC", "abc", 0},
+		{Collation{Mode: CompareDictionary}, "x007", "x7", 0},
+	} {
+		if got := tc.coll.Compare(NewTokenString(tc.a), NewTokenString(tc.b)); sign(got) != tc.want {
+			t.Errorf("%+v: Compare(%q, %q) = %d, want %d", tc.coll, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestEqual_Collections(t *testing.T) {
+	list := func(s ...string) *Token { return NewList(NewTokenListString(s)) }
+	if !list("1", "2.0").Equal(list("1.0", "2")) {
+		t.Error("lists should compare element-wise")
+	}
+	if list("1", "2").Equal(list("1", "2", "3")) {
+		t.Error("lists of different lengths shouldn't be equal")
+	}
+	d1, _ := ProcDictCreate(nil, NewTokenListString([]string{"dict", "a", "1", "b", "2"}))
+	d2, _ := ProcDictCreate(nil, NewTokenListString([]string{"dict", "b", "2.0", "a", "1"}))
+	if !d1.Equal(d2) {
+		t.Error("dicts should be equal regardless of key order")
+	}
+}
+
+func TestEqual_GoObject(t *testing.T) {
+	now := time.Now()
+	a := WrapObject(&now, nil)
+	utc := now.UTC()
+	b := WrapObject(&utc, nil)
+	if !a.Equal(b) {
+		t.Error("expected time.Time.Equal to be used")
+	}
+	later := now.Add(time.Second)
+	c := WrapObject(&later, nil)
+	if a.Compare(c) >= 0 {
+		t.Error("expected time.Time.Compare to be used")
+	}
+
+	type pt struct{ X, Y int }
+	p1, p2 := &pt{1, 2}, &pt{1, 2}
+	if !WrapObject(p1, nil).Equal(WrapObject(p2, nil)) {
+		t.Error("expected structs to compare deeply")
+	}
+}
+
+func TestCompareProcs(t *testing.T) {
+	interp := NewInterp()
+	runScripts(t, interp, []scriptTest{
+		{`eq 1 1.0`, "true"},
+		{`eq 1 1 1.0 01`, "true"},
+		{`eq 1 1 2`, "false"},
+		{`eq true on`, "true"},
+		{`eq -ascii 1 1.0`, "false"},
+		{`eq -nocase ABC abc`, "true"},
+		{`eq -- -nocase -nocase`, "true"},
+		{`ne 1 1.0`, "false"},
+		{`ne a b`, "true"},
+		{`< 2 10`, "true"},
+		{`< -ascii 2 10`, "false"},
+		{`< apple banana`, "true"},
+		{`>= -dictionary a10 a9`, "true"},
+		{`expr {"on" == true}`, "true"},
+		// lists compare by value, whatever has been cached in them
+		{`eq {1 2} {1.0 2}`, "true"},
+		{`set l {1 2}; list::len $l; eq $l {1.0 2}`, "true"},
+		{`eq [list 1 2] [list 1.0 2]`, "true"},
+		{`eq {a b} {a  b}`, "true"},
+		// and numbers and booleans compare transitively
+		{`eq 1 on`, "true"},
+		{`eq 1.0 on`, "true"},
+		{`eq 0.0 no off false`, "true"},
+		{`eq 2 on`, "false"},
+		{`list::sort {10 9 2 1.5}`, "1.5 2 9 10"},
+		{`list::sort -ascii {10 9 2}`, "10 2 9"},
+		{`list::sort -dictionary {x10 X2 x1}`, "x1 X2 x10"},
+		{`list::sort -nocase {b A c}`, "A b c"},
+		{`list::uniq {1 1.0 2 2 1}`, "1 2 1"},
+		{`list::uniq -nocase {a A b}`, "a b"},
+		{`list::contains {1 2 3} 2.0`, "true"},
+		{`list::contains -ascii {1 2 3} 2.0`, "false"},
+		{`list::contains -nocase {Foo Bar} bar`, "true"},
+		{`list::find -type exact {1 1.0 2} 1`, "1 1.0"},
+		{`list::find -type exact -matchcase false {Foo foo bar} FOO`, "Foo foo"},
+	})
+
+	for _, script := range []string{`eq -numeric 1 a`, `list::sort -numeric {1 a}`, `< -numeric a 1`} {
+		if _, err := interp.ExecString(script); !errors.Is(err, ErrExpectedNumber) {
+			t.Errorf("%s: got %v, want ErrExpectedNumber", script, err)
+		}
+	}
+}
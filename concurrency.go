@@ -0,0 +1,427 @@
+package adz
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// ChanLib holds the commands of the chan namespace, e.g. ChanLib["send"]
+// implements chan::send.
+var ChanLib = map[string]Proc{}
+
+func init() {
+	StdLib["go"] = ProcGo
+	StdLib["select"] = ProcSelect
+
+	ChanLib["new"] = ProcChanNew
+	ChanLib["send"] = ProcChanSend
+	ChanLib["recv"] = ProcChanRecv
+	ChanLib["close"] = ProcChanClose
+}
+
+// NewTokenChan returns a token carrying ch, for passing a channel to a
+// script.
+func NewTokenChan(ch chan *Token) *Token {
+	return &Token{
+		String: fmt.Sprintf("chan%p", ch),
+		Data:   ch,
+	}
+}
+
+// AsChan returns the channel tok carries, as from chan::new or
+// NewTokenChan. A channel has no string form to go back to, so a token
+// that has lost its Data isn't a channel anymore.
+func (tok *Token) AsChan() (chan *Token, error) {
+	if ch, ok := tok.Data.(chan *Token); ok {
+		return ch, nil
+	}
+	return nil, ErrExpectedChan(tok.String)
+}
+
+// detach returns a copy of tok that can be handed to another interpreter,
+// running on another goroutine, without either seeing the other's changes.
+// Only its string form is kept, except for channels, which are meant to be
+// shared. Variable references are followed.
+func detach(tok *Token) *Token {
+	if getter, ok := tok.Data.(Getter); ok {
+		if val, err := getter.Get(tok); err == nil {
+			tok = val
+		}
+	}
+	if _, ok := tok.Data.(chan *Token); ok {
+		return tok
+	}
+	return NewTokenString(tok.String)
+}
+
+// goInterp returns a new interpreter for go to run a script on. It has a
+// copy of every namespace of interp: procs are shared, though proc bodies
+// are lexed again as they're run and commands that keep state refuse calls
+// from the new interpreter, and variables are detached, so neither
+// interpreter sees what the other changes afterwards.
+func (interp *Interp) goInterp() *Interp {
+	child := NewInterp()
+	child.scripts = make(map[string]Script)
+	child.cancel()
+	child.ctx, child.cancel = interp.ctx, nil
+	child.Stdin = interp.Stdin
+	child.Stdout = interp.Stdout
+	child.Stderr = interp.Stderr
+	child.MaxCallDepth = interp.MaxCallDepth
+	child.classes = maps.Clone(interp.classes)
+
+	for name, ns := range interp.Namespaces {
+		cns, ok := child.Namespaces[name]
+		if !ok {
+			cns = NewNamespace(name)
+			child.Namespaces[name] = cns
+		}
+		maps.Copy(cns.Procs, ns.Procs)
+		for id, tok := range ns.Vars {
+			cns.Vars[id] = detach(tok)
+		}
+	}
+	return child
+}
+
+// foreign reports whether interp is of a family other than home's, as one
+// started by go is. goInterp shares every proc, so commands that keep
+// state, like coroutines, classes and closures, refuse calls from foreign
+// interpreters.
+func (interp *Interp) foreign(home *Interp) bool {
+	return interp.Mutex != home.Mutex
+}
+
+// errForeign is the error of a command refusing a foreign call.
+func errForeign(name string) error {
+	return ErrCommand(name, "belongs to another interpreter")
+}
+
+func ProcGo(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		&Argument{
+			Name:    "-vars",
+			Help:    "Variables of the calling scope to copy into the new interpreter's global namespace. Values are copied as strings, channels aside, so a variable holding a command, as closure or an object gives, arrives as just its name.",
+			Default: EmptyToken,
+		},
+		ArgHelp("script", "script to run"),
+	)
+	as.Help = "Runs {script} on a goroutine, in a new interpreter with a copy of this one's namespaces. " +
+		"Namespace variables are copied as they are when go is called; other variables must be given with -vars. " +
+		"Changes either interpreter makes aren't seen by the other, so the script and its caller communicate over channels. " +
+		"Returns a channel that receives the script's result when it finishes; if the script fails, the error is written to stderr and the channel is closed instead."
+
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+
+	child := interp.goInterp()
+	names, err := parsedArgs["vars"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["vars"].String)
+	}
+	for _, name := range names {
+		val, err := interp.GetVar(name.String)
+		if err != nil {
+			return EmptyToken, err
+		}
+		_, id := identifierParts(name.String)
+		child.Namespaces[""].Vars[id] = detach(val)
+	}
+
+	// the script is parsed again by the child, so that none of the tokens
+	// it runs are shared with this interpreter
+	script := parsedArgs["script"].String
+	done := make(chan *Token, 1)
+	go func() {
+		defer child.Close()
+		ret, err := child.ExecString(script)
+		if err != nil {
+			fmt.Fprintf(child.Stderr, "go: %v\n", err)
+			close(done)
+			return
+		}
+		done <- detach(ret)
+		close(done)
+	}()
+
+	return NewTokenChan(done), nil
+}
+
+func ProcChanNew(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		ArgDefault("size", NewTokenInt(0)),
+	)
+	as.Help = "Returns a new channel, buffered to hold {size} values."
+
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+	size, err := parsedArgs["size"].AsInt()
+	if err != nil {
+		return EmptyToken, err
+	}
+	if size < 0 {
+		return EmptyToken, ErrCommand(args[0].String, "size must not be negative")
+	}
+	return NewTokenChan(make(chan *Token, size)), nil
+}
+
+// chanSend sends val on ch, returning an error rather than panicking if ch
+// is closed, or if interp is closed while it waits.
+func chanSend(interp *Interp, ch chan *Token, val *Token) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("send on closed channel")
+		}
+	}()
+	select {
+	case ch <- val:
+		return nil
+	case <-interp.ctx.Done():
+		return ErrInterpClosed
+	}
+}
+
+func ProcChanSend(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	ch, err := args[1].AsChan()
+	if err != nil {
+		return EmptyToken, err
+	}
+	if err := chanSend(interp, ch, detach(args[2])); err != nil {
+		return EmptyToken, err
+	}
+	return EmptyToken, nil
+}
+
+// chan::recv ch ?varName? waits for a value on ch and returns it, or an
+// empty string once ch is closed. If varName is given, the value is stored
+// there instead and whether one was received is returned.
+func ProcChanRecv(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return EmptyToken, ErrArgCount("1 or 2", len(args)-1)
+	}
+	ch, err := args[1].AsChan()
+	if err != nil {
+		return EmptyToken, err
+	}
+	var val *Token
+	var ok bool
+	select {
+	case val, ok = <-ch:
+	case <-interp.ctx.Done():
+		return EmptyToken, ErrInterpClosed
+	}
+	if !ok {
+		val = EmptyToken
+	}
+	if len(args) == 2 {
+		return val, nil
+	}
+	if _, err := interp.SetVar(args[2].String, val); err != nil {
+		return EmptyToken, err
+	}
+	if ok {
+		return TrueToken, nil
+	}
+	return FalseToken, nil
+}
+
+func ProcChanClose(interp *Interp, args []*Token) (_ *Token, err error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	ch, err := args[1].AsChan()
+	if err != nil {
+		return EmptyToken, err
+	}
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("close of closed channel")
+		}
+	}()
+	close(ch)
+	return EmptyToken, nil
+}
+
+// parseDuration parses tok as a number of milliseconds, or as a duration
+// with units such as 1.5s or 100ms.
+func parseDuration(tok *Token) (time.Duration, error) {
+	if ms, err := tok.AsInt(); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(tok.String)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s", tok.Quoted())
+	}
+	return d, nil
+}
+
+// ProcSelect implements select, which waits until one of several channel
+// operations can proceed, then runs the body of that clause:
+//
+//	select {
+//		recv ch varName body
+//		send ch value body
+//		timeout duration body
+//		default body
+//	}
+//
+// A recv clause stores the value received in varName, which may instead be
+// a list of two names, the second set to whether a value was received
+// rather than the channel being closed; an empty varName discards it. A
+// timeout clause is chosen once duration, as for parseDuration, has passed;
+// a default clause, if nothing else is ready. If several clauses are
+// ready, one is chosen at random.
+//
+// The clauses can also be given as separate args. Given as a single list,
+// as above, the channel, value and duration of each clause are substituted
+// as they would be as separate args.
+func ProcSelect(interp *Interp, args []*Token) (*Token, error) {
+	var clauses []*Token
+	subst := func(tok *Token) (*Token, error) { return tok, nil }
+	switch len(args) {
+	case 1:
+		return EmptyToken, ErrArgMinimum(1, 0)
+	case 2:
+		var err error
+		if clauses, err = args[1].AsList(); err != nil {
+			return EmptyToken, ErrExpectedList(args[1].String)
+		}
+		subst = interp.Subst
+	default:
+		clauses = args[1:]
+	}
+
+	var (
+		cases    []reflect.SelectCase
+		bodies   []*Token
+		recvVars = map[int][]*Token{}
+		timers   []*time.Timer
+		dflt     bool
+	)
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	words := map[string]int{"recv": 4, "send": 4, "timeout": 3, "default": 2}
+	for i := 0; i < len(clauses); {
+		kind := clauses[i].String
+		n, ok := words[kind]
+		if !ok {
+			return EmptyToken, ErrCommand(args[0].String, fmt.Sprintf("unknown clause %s, expected recv, send, timeout or default", clauses[i].Quoted()))
+		}
+		if i+n > len(clauses) {
+			return EmptyToken, ErrExpectedMore(fmt.Sprintf("%d args", n-1), kind)
+		}
+		clause := slices.Clone(clauses[i : i+n])
+		i += n
+		if kind != "default" {
+			var err error
+			if clause[1], err = subst(clause[1]); err != nil {
+				return EmptyToken, err
+			}
+		}
+		if kind == "send" {
+			var err error
+			if clause[2], err = subst(clause[2]); err != nil {
+				return EmptyToken, err
+			}
+		}
+
+		var sc reflect.SelectCase
+		switch kind {
+		case "recv", "send":
+			ch, err := clause[1].AsChan()
+			if err != nil {
+				return EmptyToken, err
+			}
+			sc.Chan = reflect.ValueOf(ch)
+			if kind == "send" {
+				sc.Dir = reflect.SelectSend
+				sc.Send = reflect.ValueOf(detach(clause[2]))
+				break
+			}
+			sc.Dir = reflect.SelectRecv
+			vars, err := clause[2].AsList()
+			if err != nil || len(vars) > 2 {
+				return EmptyToken, ErrCommand(args[0].String, fmt.Sprintf("expected a variable name or a list of two, got %s", clause[2].Quoted()))
+			}
+			recvVars[len(cases)] = vars
+		case "timeout":
+			d, err := parseDuration(clause[1])
+			if err != nil {
+				return EmptyToken, err
+			}
+			t := time.NewTimer(d)
+			timers = append(timers, t)
+			sc.Dir = reflect.SelectRecv
+			sc.Chan = reflect.ValueOf(t.C)
+		case "default":
+			if dflt {
+				return EmptyToken, ErrCommand(args[0].String, "only one default clause is allowed")
+			}
+			dflt = true
+			sc.Dir = reflect.SelectDefault
+		}
+		cases = append(cases, sc)
+		bodies = append(bodies, clause[n-1])
+	}
+
+	// waiting ends if interp is closed
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(interp.ctx.Done())})
+	chosen, val, ok, err := selectCases(cases)
+	if err != nil {
+		return EmptyToken, err
+	}
+	if chosen == len(bodies) {
+		return EmptyToken, ErrInterpClosed
+	}
+
+	if vars, isRecv := recvVars[chosen]; isRecv {
+		tok := EmptyToken
+		if ok {
+			tok = val.Interface().(*Token)
+		}
+		if len(vars) > 0 && vars[0].String != "" {
+			if _, err := interp.SetVar(vars[0].String, tok); err != nil {
+				return EmptyToken, err
+			}
+		}
+		if len(vars) > 1 {
+			okTok := FalseToken
+			if ok {
+				okTok = TrueToken
+			}
+			if _, err := interp.SetVar(vars[1].String, okTok); err != nil {
+				return EmptyToken, err
+			}
+		}
+	}
+
+	return interp.ExecToken(bodies[chosen])
+}
+
+// selectCases is reflect.Select, returning an error rather than panicking
+// if a send case's channel is closed.
+func selectCases(cases []reflect.SelectCase) (chosen int, val reflect.Value, ok bool, err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("send on closed channel")
+		}
+	}()
+	chosen, val, ok = reflect.Select(cases)
+	return
+}
@@ -0,0 +1,112 @@
+package adz
+
+import (
+	"testing"
+)
+
+func TestChan(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`set c [chan::new 1]; chan::send $c hi; chan::recv $c`, "hi"},
+		{`set c [chan::new 2]; chan::send $c a; chan::send $c b; chan::close $c; list [chan::recv $c] [chan::recv $c v] $v [chan::recv $c v] $v`, "a true b false {}"},
+		{`set c [chan::new]; chan::close $c; chan::recv $c`, ""},
+		// values are sent as strings
+		{`set c [chan::new 1]; chan::send $c [list a {b c}]; list::len [chan::recv $c]`, "2"},
+	})
+
+	for _, script := range []string{
+		`chan::new -1`,
+		`chan::send notachan x`,
+		`chan::recv notachan`,
+		`set c [chan::new 1]; chan::close $c; chan::send $c x`,
+		`set c [chan::new]; chan::close $c; chan::close $c`,
+	} {
+		interp := NewInterp()
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestChan_FromGo(t *testing.T) {
+	interp := NewInterp()
+	ch := make(chan *Token)
+	interp.SetVar("c", NewTokenChan(ch))
+	go func() {
+		for i := range 3 {
+			ch <- NewTokenInt(i)
+		}
+		close(ch)
+	}()
+	out, err := interp.ExecString(`set acc {}; while {chan::recv $c v} { list::append acc $v }; return $acc`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String != "0 1 2" {
+		t.Errorf("expected %q, got %q", "0 1 2", out.String)
+	}
+}
+
+func TestGo(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`chan::recv [go {+ 1 2}]`, "3"},
+		{`set c [chan::new]; go -vars c { chan::send $c [+ 20 22] }; chan::recv $c`, "42"},
+		// procs and namespace variables are copied
+		{`proc double {x} { * $x 2 }; set g 5; chan::recv [go {double $g}]`, "10"},
+		{`namespace ns { set v nsvar; proc get {} { return $::ns::v } }; chan::recv [go {ns::get}]`, "nsvar"},
+		// but changes to them aren't seen by the other
+		{`set x 1; chan::recv [go {set x 2}]; return $x`, "1"},
+		{`proc f {} { return parent }; chan::recv [go {proc f {} { return child }; f}]; f`, "parent"},
+		// a failed script closes its channel
+		{`list [chan::recv [go {throw oops}] v] $v`, "false {}"},
+		// fan in
+		{`set c [chan::new]
+		  foreach i {1 2 3 4 5} { go -vars {c i} { chan::send $c [* $i $i] } }
+		  set sum 0; foreach i {1 2 3 4 5} { set sum [+ $sum [chan::recv $c]] }; return $sum`, "55"},
+		// pipeline
+		{`set in [chan::new]; set out [chan::new]
+		  go -vars {in out} { while {chan::recv $in v} { chan::send $out [+ $v 1] }; chan::close $out }
+		  go -vars in { foreach v {1 2 3} { chan::send $in $v }; chan::close $in }
+		  set acc {}; while {chan::recv $out v} { list::append acc $v }; return $acc`, "2 3 4"},
+	})
+
+	interp := NewInterp()
+	if _, err := interp.ExecString(`go -vars nosuchvar {}`); err == nil {
+		t.Error("expected error exporting a missing variable")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`set a [chan::new 1]; set b [chan::new 1]; chan::send $b hi
+		  select { recv $a v {return a:$v} recv $b v {return b:$v} }`, "b:hi"},
+		{`set a [chan::new]; select { recv $a v {return got} default {return none} }`, "none"},
+		{`set a [chan::new]; select { recv $a v {return got} timeout 10 {return late} }`, "late"},
+		{`set a [chan::new]; select { recv $a v {return got} timeout 5ms {return late} }`, "late"},
+		{`set a [chan::new 1]; select { send $a x {return sent} default {return full} }`, "sent"},
+		{`set a [chan::new 1]; chan::send $a x; select { send $a y {return sent} default {return full} }`, "full"},
+		{`set a [chan::new]; chan::close $a; select { recv $a {v ok} {return [list $v $ok]} }`, "{} false"},
+		{`set a [chan::new 1]; chan::send $a x; select { recv $a {} {return discarded} }`, "discarded"},
+		// inline clauses
+		{`set a [chan::new 1]; chan::send $a x; select recv $a v {return $v}`, "x"},
+		// with go
+		{`set c [chan::new]; go -vars c { chan::send $c done }; select { recv $c v {return $v} timeout 5s {return timeout} }`, "done"},
+		// flow control from bodies
+		{`set c [chan::new 3]; foreach v {1 2 3} { chan::send $c $v }; chan::close $c
+		  set acc {}; while true { select { recv $c {v ok} { if {not $ok} {break}; list::append acc $v } } }; return $acc`, "1 2 3"},
+	})
+
+	for _, script := range []string{
+		`select`,
+		`select {recv}`,
+		`select {wait 1 {}}`,
+		`select {recv notachan v {}}`,
+		`select {timeout soon {}}`,
+		`select {default {} default {}}`,
+		`set a [chan::new]; chan::close $a; select { send $a x {} }`,
+	} {
+		interp := NewInterp()
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
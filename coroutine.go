@@ -0,0 +1,319 @@
+package adz
+
+import (
+	"fmt"
+)
+
+func init() {
+	StdLib["coroutine"] = ProcCoroutine
+	StdLib["yield"] = ProcYield
+	StdLib["yieldto"] = ProcYieldTo
+	InfoLib["coroutine"] = ProcInfoCoroutine
+}
+
+// coYield is what a coroutine hands back to whoever resumed it.
+type coYield struct {
+	val *Token
+	err error
+	// val is a command to run in place of the resuming command; see yieldto
+	yieldto bool
+	// the coroutine's command returned; val and err are its result
+	done bool
+}
+
+// Coroutine is a command run on a goroutine of its own so that it can be
+// suspended, by yield or yieldto, and later resumed where it left off.
+// Only one of a coroutine and whoever resumed it runs at any time: they
+// hand control back and forth over channels. The coroutine runs in an
+// execution context of its own, so it keeps its own Stack and Frame between
+// resumptions; see Interp.enter.
+//
+// A Coroutine is a Procer; calling it resumes it. Deleting or replacing its
+// command ends it: the yield it's suspended in returns an error, so that it
+// unwinds and its goroutine exits. An interpreter dropped with coroutines
+// that haven't returned keeps their goroutines unless it's closed; see
+// Interp.Close.
+type Coroutine struct {
+	// Name is the qualified name of the command that resumes the coroutine.
+	Name string
+
+	interp *Interp
+	resume chan []*Token
+	yield  chan coYield
+
+	running bool
+	done    bool
+	// its command was deleted or replaced; see kill
+	killed bool
+	// suspended by yieldto, so resuming takes any number of args
+	yieldedTo bool
+}
+
+// newCoroutine returns a coroutine that will run cmd, a command already
+// substituted, in the root frame of the current namespace. The coroutine
+// doesn't run until it's first resumed.
+func (interp *Interp) newCoroutine(name string, cmd Command) *Coroutine {
+	co := &Coroutine{
+		Name:   name,
+		resume: make(chan []*Token),
+		yield:  make(chan coYield),
+	}
+
+	ns := interp.Frame.localNamespace
+	ctx := *interp
+	ctx.Stack = []*Frame{}
+	ctx.Frame = &Frame{
+		localNamespace: ns,
+		localVars:      ns.Vars,
+		localProcs:     ns.Procs,
+		namespaceRoot:  true,
+	}
+	ctx.calldepth = 0
+	ctx.tailcall = nil
+	ctx.coroutine = co
+	co.interp = &ctx
+
+	go func() {
+		if _, ok := <-co.resume; !ok {
+			// killed before it ever ran
+			co.yield <- coYield{val: EmptyToken, done: true}
+			return
+		}
+		ret, err := co.run(cmd)
+		co.yield <- coYield{val: ret, err: err, done: true}
+	}()
+	return co
+}
+
+func (co *Coroutine) run(cmd Command) (tok *Token, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			tok, err = EmptyToken, ErrGoPanic(x)
+		}
+	}()
+	tok, err = co.interp.ExecLiteral(cmd)
+	if err == ErrReturn {
+		err = nil
+	}
+	return
+}
+
+// Token returns a token naming co whose Data is co, so it can be called
+// directly or consumed by foreach.
+func (co *Coroutine) Token() *Token {
+	return &Token{String: co.Name, Data: co}
+}
+
+// Done reports whether co's command has returned.
+func (co *Coroutine) Done() bool {
+	return co.done
+}
+
+// Proc resumes co, passing args[1:] to the yield or yieldto that suspended
+// it, and returns the next value it yields. When co's command returns, its
+// result is returned instead and co's command is deleted.
+func (co *Coroutine) Proc(interp *Interp, args []*Token) (*Token, error) {
+	return co.resumeWith(interp, args[1:])
+}
+
+// resumeWith resumes co, as Proc does, with vals passed to the yield or
+// yieldto that suspended it. interp is the resuming command's, where a
+// yieldto runs its command.
+func (co *Coroutine) resumeWith(interp *Interp, vals []*Token) (*Token, error) {
+	switch {
+	case interp.foreign(co.interp):
+		return EmptyToken, errForeign(co.Name)
+	case co.done:
+		return EmptyToken, fmt.Errorf("coroutine %s has finished", co.Name)
+	case co.running:
+		return EmptyToken, fmt.Errorf("coroutine %s is already running", co.Name)
+	case !co.yieldedTo && len(vals) > 1:
+		return EmptyToken, ErrArgCount("0 or 1", len(vals))
+	}
+	if vals == nil {
+		vals = []*Token{}
+	}
+
+	y := co.handoff(func() { co.resume <- vals })
+	co.yieldedTo = y.yieldto
+
+	if y.done {
+		co.done = true
+		co.delete(co.interp)
+	}
+	if y.yieldto {
+		cmd, _ := y.val.AsList()
+		return interp.ExecLiteral(cmd)
+	}
+	return y.val, y.err
+}
+
+// handoff runs co until it yields or returns. wake is what resumes it.
+func (co *Coroutine) handoff(wake func()) coYield {
+	co.running = true
+	wake()
+	y := <-co.yield
+	co.running = false
+	return y
+}
+
+// kill ends co as its command is deleted or replaced. The yield or yieldto
+// co is suspended in returns an error, as does any it reaches after, and co
+// runs until its command returns. If co is running, it's the one deleting
+// its command, so it's only marked.
+func (co *Coroutine) kill() {
+	if co.done || co.killed {
+		return
+	}
+	co.killed = true
+	if co.running {
+		return
+	}
+	co.handoff(func() { close(co.resume) })
+	co.done = true
+}
+
+// suspend hands y to whoever resumed co and waits to be resumed again,
+// returning the values it's resumed with, or an error if co is killed
+// instead. It must be called on co's goroutine.
+func (co *Coroutine) suspend(y coYield) ([]*Token, error) {
+	if co.killed {
+		return nil, fmt.Errorf("coroutine %s was deleted", co.Name)
+	}
+	co.yield <- y
+	vals, ok := <-co.resume
+	if !ok {
+		return nil, fmt.Errorf("coroutine %s was deleted", co.Name)
+	}
+	return vals, nil
+}
+
+// delete deletes co's command, on behalf of interp, unless it's been
+// replaced, ending co if it hasn't returned.
+func (co *Coroutine) delete(interp *Interp) {
+	if interp.root.coroutines[co.Name] == co {
+		interp.Proc(co.Name, nil)
+		return
+	}
+	co.kill()
+}
+
+// dropCoroutine kills the coroutine whose command is name, a qualified
+// name, if there is one, as that command is deleted or replaced.
+func (interp *Interp) dropCoroutine(name string) {
+	co, ok := interp.root.coroutines[name]
+	if !ok {
+		return
+	}
+	delete(interp.root.coroutines, name)
+	co.kill()
+}
+
+// ProcCoroutine implements coroutine:
+//
+//	coroutine name cmd ?arg ...?
+//
+// It creates the command name, then runs cmd with args until it yields,
+// returning the yielded value. Each call of name resumes cmd until it
+// yields again, returning that value, or until cmd returns, returning its
+// result; name is then deleted.
+//
+// A coroutine can be used as a generator: foreach consumes the values it
+// yields until it returns. For that, foreach needs a token that carries the
+// coroutine, as info coroutine gives, so generators commonly begin by
+// yielding it:
+//
+//	proc count {n} { yield [info coroutine]; for {set i 0} {< $i $n} {incr i} { yield $i } }
+//	foreach x [coroutine c count 3] { print $x }
+func ProcCoroutine(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 3 {
+		return EmptyToken, ErrArgMinimum(2, len(args)-1)
+	}
+	ns, id, err := interp.ResolveIdentifier(args[1].String, true)
+	if err != nil {
+		return EmptyToken, err
+	}
+	if _, ok := ns.Procs[id]; ok {
+		return EmptyToken, fmt.Errorf("command %s already exists", args[1].String)
+	}
+
+	proc, ok := interp.getProc(args[2])
+	if !ok {
+		return EmptyToken, ErrCommandNotFound(args[2].String)
+	}
+	cmd := append([]*Token{{String: args[2].String, Data: proc}}, args[3:]...)
+
+	co := interp.newCoroutine(ns.Qualified(id), cmd)
+	ns.Procs[id] = co.Proc
+	interp.root.coroutines[co.Name] = co
+	return co.resumeWith(interp, nil)
+}
+
+// ProcYield implements yield:
+//
+//	yield ?value?
+//
+// It suspends the running coroutine, making value the result of the
+// command that resumed it. When the coroutine is next resumed, yield
+// returns the value it's resumed with.
+func ProcYield(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) > 2 {
+		return EmptyToken, ErrArgCount("0 or 1", len(args)-1)
+	}
+	co := interp.coroutine
+	if co == nil {
+		return EmptyToken, fmt.Errorf("yield called outside a coroutine")
+	}
+	val := EmptyToken
+	if len(args) == 2 {
+		val = args[1]
+	}
+	in, err := co.suspend(coYield{val: val})
+	if err != nil {
+		return EmptyToken, err
+	}
+	if len(in) == 0 {
+		return EmptyToken, nil
+	}
+	return in[0], nil
+}
+
+// ProcYieldTo implements yieldto:
+//
+//	yieldto cmd ?arg ...?
+//
+// It suspends the running coroutine and runs cmd in its place in the
+// command that resumed it, as tailcall would. The coroutine can then be
+// resumed with any number of args, which yieldto returns as a list.
+func ProcYieldTo(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	co := interp.coroutine
+	if co == nil {
+		return EmptyToken, fmt.Errorf("yieldto called outside a coroutine")
+	}
+	proc, ok := interp.getProc(args[1])
+	if !ok {
+		return EmptyToken, ErrCommandNotFound(args[1].String)
+	}
+	cmd := append([]*Token{{String: args[1].String, Data: proc}}, args[2:]...)
+	in, err := co.suspend(coYield{val: NewList(cmd), yieldto: true})
+	if err != nil {
+		return EmptyToken, err
+	}
+	return NewList(in), nil
+}
+
+// ProcInfoCoroutine implements info coroutine, which returns the name of
+// the running coroutine, or an empty string outside of one. The result
+// carries the coroutine itself; see ProcCoroutine.
+func ProcInfoCoroutine(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 1 {
+		return EmptyToken, ErrArgCount(0, len(args)-1)
+	}
+	if interp.coroutine == nil {
+		return EmptyToken, nil
+	}
+	return interp.coroutine.Token(), nil
+}
@@ -0,0 +1,139 @@
+package adz
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCoroutine(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		// values out
+		{`proc gen {} { yield a; yield b; return c }; list [coroutine g gen] [g] [g]`, "a b c"},
+		// and values in
+		{`proc acc {} { set sum 0; while true { set sum [+ $sum [yield $sum]] } }; coroutine a acc; a 1; a 2; a 3`, "6"},
+		// the coroutine's frame lasts between resumptions
+		{`proc f {x} { set y [+ $x 1]; yield $y; yield [+ $x $y] }; coroutine c f 1; c`, "3"},
+		// and is separate from the caller's
+		{`proc f {} { set x inner; yield; return $x }; set x outer; coroutine c f; list [c] $x`, "inner outer"},
+		// yields from deeper calls
+		{`proc inner {} { yield deep; return x }; proc f {} { inner; return done }; list [coroutine c f] [c]`, "deep done"},
+		// the command goes away when it finishes
+		{`proc f {} { yield 1 }; coroutine c f; c; catch {c}`, "true"},
+		// arguments to cmd, and commands that never yield
+		{`coroutine c list a b`, "a b"},
+		// info coroutine
+		{`proc f {} { yield [info coroutine] }; list [coroutine c f] [info coroutine]`, "::c {}"},
+		{`namespace ns { proc f {} { yield [info coroutine] }; coroutine c f }`, "::ns::c"},
+		// coroutines resuming each other
+		{`proc inner {} { yield 1; yield 2 }; proc outer {} { yield [coroutine i inner]; yield [i] }; list [coroutine o outer] [o]`, "1 2"},
+		// yieldto runs a command in the resumer's place, and returns what it's resumed with
+		{`proc f {} { set got [yieldto list x y]; return $got }; list [coroutine c f] [c 1 2 3]`, "{x y} {1 2 3}"},
+		// a tailcall within a coroutine
+		{`proc h {} { yield h; return end }; proc f {} { tailcall h }; list [coroutine c f] [c]`, "h end"},
+	} {
+		interp := NewInterp()
+		frame := interp.Frame
+		out, err := interp.ExecString(tc.script)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.script, err)
+			continue
+		}
+		if out.String != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.want, out.String)
+		}
+		if interp.CallDepth() != 0 || len(interp.Stack) != 0 || interp.Frame != frame {
+			t.Errorf("%s: interpreter state not restored", tc.script)
+		}
+	}
+}
+
+func TestCoroutine_Errors(t *testing.T) {
+	for _, script := range []string{
+		`yield 1`,
+		`yieldto list`,
+		`coroutine c`,
+		`coroutine c nosuchcmd`,
+		`proc f {} { yield }; coroutine c f; coroutine c f`,
+		`proc f {} { c }; coroutine c f`,
+		`proc f {} { yield }; coroutine c f; c 1 2`,
+		`proc f {} { yield; throw oops }; coroutine c f; c`,
+	} {
+		interp := NewInterp()
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+		if interp.CallDepth() != 0 || len(interp.Stack) != 0 {
+			t.Errorf("%s: interpreter state not restored", script)
+		}
+	}
+}
+
+func TestCoroutine_Generator(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`proc count {n} { yield [info coroutine]; set i 0; while {< $i $n} { yield $i; incr i } }
+		  set acc {}; foreach x [coroutine c count 4] { list::append acc $x }; return $acc`, "0 1 2 3"},
+		// several at a time
+		{`proc count {n} { yield [info coroutine]; set i 0; while {< $i $n} { yield $i; incr i } }
+		  set acc {}; foreach {a b} [coroutine c count 5] { list::append acc [list $a $b] }; return $acc`, "{0 1} {2 3} {4 {}}"},
+		// a generator left by break can be picked up again
+		{`proc count {} { yield [info coroutine]; set i 0; while true { yield $i; incr i } }
+		  set g [coroutine c count]
+		  foreach x $g { if {== $x 2} {break} }
+		  set acc {}; foreach x $g { list::append acc $x; if {== $x 5} {break} }; return $acc`, "3 4 5"},
+		// nothing left
+		{`proc none {} { yield [info coroutine] }; set acc {}; foreach x [coroutine c none] { list::append acc $x }; return $acc`, ""},
+	})
+
+	interp := NewInterp()
+	_, err := interp.ExecString(`proc bad {} { yield [info coroutine]; yield 1; throw oops }; foreach x [coroutine c bad] {}`)
+	if err == nil {
+		t.Error("expected a generator's error to end foreach")
+	}
+}
+
+func TestCoroutine_Deleted(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		// the yield it's suspended in fails, and the command is free
+		{`proc f {} { catch {yield 1} _ err; set ::err $err }; coroutine c f; proc c {} { return new }; list $err [c]`, "{yield: coroutine ::c was deleted} new"},
+		// even from within
+		{`proc f {} { proc ::c {} { return new }; catch {yield 1} _ err; return $err }; list [coroutine c f] [c]`, "{yield: coroutine ::c was deleted} new"},
+		// a finished coroutine leaves a replacement alone
+		{`proc f {} { proc ::c {} { return new }; return old }; list [coroutine c f] [c]`, "old new"},
+	})
+}
+
+func TestCoroutine_Leak(t *testing.T) {
+	const gen = `proc count {} { yield [info coroutine]; set i 0; while true { yield $i; incr i } }`
+	for _, tc := range []struct {
+		name    string
+		abandon func(t *testing.T, interp *Interp)
+	}{
+		{"foreach break", func(t *testing.T, interp *Interp) {
+			mustRun(t, interp, `foreach x [coroutine c count] { if {== $x 2} {break} }; proc c {} {}`)
+		}},
+		{"Proc nil", func(t *testing.T, interp *Interp) {
+			mustRun(t, interp, `coroutine c count; c`)
+			if err := interp.Proc("c", nil); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"dropped interp", func(t *testing.T, interp *Interp) {
+			mustRun(t, interp, `coroutine c count; coroutine d count`)
+			interp.Close()
+		}},
+	} {
+		before := runtime.NumGoroutine()
+		interp := NewInterp()
+		mustRun(t, interp, gen)
+		tc.abandon(t, interp)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := runtime.NumGoroutine(); n > before {
+			t.Errorf("%s: %d goroutines left running", tc.name, n-before)
+		}
+	}
+}
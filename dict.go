@@ -0,0 +1,420 @@
+package adz
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+var DictLib = map[string]Proc{}
+
+func init() {
+	StdLib["dict"] = ProcDict
+
+	DictLib["create"] = ProcDictCreate
+	DictLib["get"] = ProcDictGet
+	DictLib["set"] = ProcDictSet
+	DictLib["unset"] = ProcDictUnset
+	DictLib["exists"] = ProcDictExists
+	DictLib["keys"] = ProcDictKeys
+	DictLib["values"] = ProcDictValues
+	DictLib["merge"] = ProcDictMerge
+	DictLib["for"] = ProcDictFor
+	DictLib["map"] = ProcDictMap
+	DictLib["filter"] = ProcDictFilter
+	DictLib["size"] = ProcDictSize
+}
+
+// Dict is a dictionary that remembers the order keys were first added in.
+// Its string form is a key/value list in that order, so any even-length list
+// can be read as a Dict and a Dict can be read as a list.
+//
+// Dicts are values: once stored in a token they must not be modified. Use
+// Clone to get a copy to change.
+type Dict struct {
+	keys []string
+	vals map[string]*Token
+}
+
+func NewDict() *Dict {
+	return &Dict{vals: make(map[string]*Token)}
+}
+
+// Get returns the value stored under key.
+func (d *Dict) Get(key string) (*Token, bool) {
+	val, ok := d.vals[key]
+	return val, ok
+}
+
+// Set stores val under key. A new key goes at the end; an existing key keeps
+// its position.
+func (d *Dict) Set(key string, val *Token) {
+	if _, ok := d.vals[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.vals[key] = val
+}
+
+// Unset removes key.
+func (d *Dict) Unset(key string) {
+	if _, ok := d.vals[key]; !ok {
+		return
+	}
+	delete(d.vals, key)
+	d.keys = slices.DeleteFunc(d.keys, func(k string) bool { return k == key })
+}
+
+// Keys returns the keys in order. The slice must not be modified.
+func (d *Dict) Keys() []string {
+	return d.keys
+}
+
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+func (d *Dict) Clone() *Dict {
+	c := &Dict{
+		keys: slices.Clone(d.keys),
+		vals: make(map[string]*Token, len(d.vals)),
+	}
+	for k, v := range d.vals {
+		c.vals[k] = v
+	}
+	return c
+}
+
+// List returns the dict as a key/value list.
+func (d *Dict) List() List {
+	list := make(List, 0, 2*len(d.keys))
+	for _, k := range d.keys {
+		list = append(list, NewTokenString(k), d.vals[k])
+	}
+	return list
+}
+
+func (d *Dict) MarshalToken() (*Token, error) {
+	tok, err := d.List().MarshalToken()
+	if err != nil {
+		return EmptyToken, err
+	}
+	return &Token{String: tok.String, Data: d}, nil
+}
+
+// Token returns a token holding d.
+func (d *Dict) Token() *Token {
+	tok, _ := d.MarshalToken()
+	return tok
+}
+
+// AsDict interprets tok as a Dict. Data already holding a Dict or a Map is
+// used directly; otherwise tok is parsed as a key/value list, with later
+// duplicate keys replacing earlier values.
+func (tok *Token) AsDict() (*Dict, error) {
+	switch data := tok.Data.(type) {
+	case *Dict:
+		return data, nil
+	case Map:
+		d := NewDict()
+		for k, v := range data {
+			d.Set(k, v)
+		}
+		slices.Sort(d.keys)
+		return d, nil
+	}
+
+	list, err := tok.AsList()
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%2 != 0 {
+		return nil, fmt.Errorf("cannot use as dict: need even number of elements, got %d", len(list))
+	}
+	// not cached in Data: a Dict there marks tok as a dict, indexed by key
+	// rather than as a list; see elemIsList
+	d := NewDict()
+	for i := 0; i < len(list); i += 2 {
+		d.Set(list[i].String, list[i+1])
+	}
+	return d, nil
+}
+
+// dictGetPath follows keys down through nested dicts.
+func dictGetPath(tok *Token, keys []*Token) (*Token, error) {
+	for _, key := range keys {
+		d, err := tok.AsDict()
+		if err != nil {
+			return EmptyToken, err
+		}
+		val, ok := d.Get(key.String)
+		if !ok {
+			return EmptyToken, fmt.Errorf("key %q not known in dictionary", key.String)
+		}
+		tok = val
+	}
+	return tok, nil
+}
+
+// dictUpdatePath returns a copy of tok, read as a dict, with the value at
+// keys replaced by update's result. Dicts along the way are copied rather
+// than modified; missing ones are created.
+func dictUpdatePath(tok *Token, keys []*Token, update func(d *Dict, key string)) (*Token, error) {
+	d, err := tok.AsDict()
+	if err != nil {
+		return EmptyToken, err
+	}
+	d = d.Clone()
+	key := keys[0].String
+	if len(keys) == 1 {
+		update(d, key)
+		return d.Token(), nil
+	}
+	child, ok := d.Get(key)
+	if !ok {
+		child = EmptyToken
+	}
+	child, err = dictUpdatePath(child, keys[1:], update)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", key, err)
+	}
+	d.Set(key, child)
+	return d.Token(), nil
+}
+
+// ProcDict dispatches dict subcommand ?arg ...? to dict::subcommand.
+func ProcDict(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, 0)
+	}
+	sub, ok := DictLib[args[1].String]
+	if !ok {
+		return EmptyToken, ErrCommand("dict", fmt.Sprintf("unknown subcommand %q", args[1].String))
+	}
+	return sub(interp, args[1:])
+}
+
+// dict create ?key value ...?
+func ProcDictCreate(interp *Interp, args []*Token) (*Token, error) {
+	if len(args)%2 != 1 {
+		return EmptyToken, fmt.Errorf("%s: expected key value pairs", args[0].String)
+	}
+	d := NewDict()
+	for i := 1; i < len(args); i += 2 {
+		d.Set(args[i].String, args[i+1])
+	}
+	return d.Token(), nil
+}
+
+// dict get dict ?key ...?
+func ProcDictGet(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	if len(args) == 2 {
+		d, err := args[1].AsDict()
+		if err != nil {
+			return EmptyToken, err
+		}
+		return d.Token(), nil
+	}
+	return dictGetPath(args[1], args[2:])
+}
+
+// dict set varName key ?key ...? value
+func ProcDictSet(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 4 {
+		return EmptyToken, ErrArgMinimum(3, len(args)-1)
+	}
+	val := args[len(args)-1]
+	return dictUpdateVar(interp, args[1].String, args[2:len(args)-1], func(d *Dict, key string) {
+		d.Set(key, val)
+	})
+}
+
+// dict unset varName key ?key ...?
+func ProcDictUnset(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 3 {
+		return EmptyToken, ErrArgMinimum(2, len(args)-1)
+	}
+	return dictUpdateVar(interp, args[1].String, args[2:], func(d *Dict, key string) {
+		d.Unset(key)
+	})
+}
+
+// dictUpdateVar applies update at keys within the dict in the variable
+// varName, which is created if it doesn't exist, and stores the result back.
+func dictUpdateVar(interp *Interp, varName string, keys []*Token, update func(d *Dict, key string)) (*Token, error) {
+	dictVar, err := interp.GetVar(varName)
+	if err != nil {
+		// bubble up any error that isn't ErrNoVar
+		if !errors.Is(err, ErrNoVar) {
+			return EmptyToken, err
+		}
+		dictVar = EmptyToken
+	}
+	newDict, err := dictUpdatePath(dictVar, keys, update)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", varName, err)
+	}
+	return interp.SetVar(varName, newDict)
+}
+
+// dict exists dict key ?key ...?
+func ProcDictExists(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 3 {
+		return EmptyToken, ErrArgMinimum(2, len(args)-1)
+	}
+	if _, err := dictGetPath(args[1], args[2:]); err != nil {
+		return FalseToken, nil
+	}
+	return TrueToken, nil
+}
+
+// dict keys dict ?pattern?
+func ProcDictKeys(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return EmptyToken, ErrArgCount("1 or 2", len(args)-1)
+	}
+	d, err := args[1].AsDict()
+	if err != nil {
+		return EmptyToken, err
+	}
+	keys := make([]string, 0, d.Len())
+	for _, k := range d.Keys() {
+		if len(args) == 3 {
+			match, err := filepath.Match(args[2].String, k)
+			if err != nil {
+				return EmptyToken, fmt.Errorf("glob pattern: %w", err)
+			}
+			if !match {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	return NewList(NewTokenListString(keys)), nil
+}
+
+// dict values dict
+func ProcDictValues(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	d, err := args[1].AsDict()
+	if err != nil {
+		return EmptyToken, err
+	}
+	vals := make([]*Token, 0, d.Len())
+	for _, k := range d.Keys() {
+		vals = append(vals, d.vals[k])
+	}
+	return NewList(vals), nil
+}
+
+// dict merge ?dict ...?
+func ProcDictMerge(interp *Interp, args []*Token) (*Token, error) {
+	merged := NewDict()
+	for i, arg := range args[1:] {
+		d, err := arg.AsDict()
+		if err != nil {
+			return EmptyToken, fmt.Errorf("arg %d: %w", i+1, err)
+		}
+		for _, k := range d.Keys() {
+			merged.Set(k, d.vals[k])
+		}
+	}
+	return merged.Token(), nil
+}
+
+// dict size dict
+func ProcDictSize(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	d, err := args[1].AsDict()
+	if err != nil {
+		return EmptyToken, err
+	}
+	return NewTokenInt(d.Len()), nil
+}
+
+// dictIterate runs body for each entry of the dict args[2] with the variables
+// named in args[1] set to the key and value. fn gets each result; it isn't
+// called for entries where the body used continue. break stops early.
+func dictIterate(interp *Interp, args []*Token, fn func(key string, val, ret *Token) error) error {
+	if len(args) != 4 {
+		return ErrArgCount(3, len(args)-1)
+	}
+	vars, err := args[1].AsList()
+	if err != nil {
+		return err
+	}
+	if len(vars) != 2 {
+		return fmt.Errorf("expected {keyVar valueVar}, got %d variables", len(vars))
+	}
+	d, err := args[2].AsDict()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range d.Keys() {
+		interp.SetVar(vars[0].String, NewTokenString(k))
+		interp.SetVar(vars[1].String, d.vals[k])
+		ret, err := interp.ExecToken(args[3])
+		switch err {
+		case nil:
+			if err := fn(k, d.vals[k], ret); err != nil {
+				return err
+			}
+		case ErrContinue:
+		case ErrBreak:
+			return nil
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// dict for {keyVar valueVar} dict body
+func ProcDictFor(interp *Interp, args []*Token) (*Token, error) {
+	ret := EmptyToken
+	err := dictIterate(interp, args, func(_ string, _, r *Token) error {
+		ret = r
+		return nil
+	})
+	return ret, err
+}
+
+// dict map {keyVar valueVar} dict body
+func ProcDictMap(interp *Interp, args []*Token) (*Token, error) {
+	out := NewDict()
+	err := dictIterate(interp, args, func(k string, _, ret *Token) error {
+		out.Set(k, ret)
+		return nil
+	})
+	if err != nil {
+		return EmptyToken, err
+	}
+	return out.Token(), nil
+}
+
+// dict filter {keyVar valueVar} dict body
+func ProcDictFilter(interp *Interp, args []*Token) (*Token, error) {
+	out := NewDict()
+	err := dictIterate(interp, args, func(k string, val, ret *Token) error {
+		keep, err := ret.AsBool()
+		if err != nil {
+			return fmt.Errorf("filter body: %w", err)
+		}
+		if keep {
+			out.Set(k, val)
+		}
+		return nil
+	})
+	if err != nil {
+		return EmptyToken, err
+	}
+	return out.Token(), nil
+}
@@ -0,0 +1,66 @@
+package adz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDict(t *testing.T) {
+	interp := NewInterp()
+
+	runScripts(t, interp, []scriptTest{
+		{`set d [dict create b 2 a 1]`, "b 2 a 1"},
+		{`dict get $d a`, "1"},
+		{`dict keys $d`, "b a"},
+		{`dict values $d`, "2 1"},
+		{`dict size $d`, "2"},
+		{`dict set d b 20`, "b 20 a 1"},
+		{`dict set d c x 1`, "b 20 a 1 c {x 1}"},
+		{`dict set d c y 2; dict get $d c y`, "2"},
+		{`dict exists $d c x`, "true"},
+		{`dict exists $d c z`, "false"},
+		{`dict unset d c x`, "b 20 a 1 c {y 2}"},
+		{`dict unset d nosuch`, "b 20 a 1 c {y 2}"},
+		{`dict merge {a 1 b 2} {b 3 c 4}`, "a 1 b 3 c 4"},
+		{`dict keys {apple 1 banana 2 avocado 3} a*`, "apple avocado"},
+		{`dict get {a 1 b 2 a 3}`, "a 3 b 2"},
+		{`dict::size {a 1}`, "1"},
+		{`dict set fresh k v`, "k v"},
+		{`set n 0; dict for {k v} {a 1 b 2 c 3} { if {eq $k b} { continue }; set n [+ $n $v] }; return $n`, "4"},
+		{`set ks {}; dict for {k v} {a 1 b 2 c 3} { if {eq $k b} { break }; list::append ks $k }; return $ks`, "a"},
+		{`dict map {k v} {a 1 b 2} { * $v 10 }`, "a 10 b 20"},
+		{`dict filter {k v} {a 1 b 2 c 3} { not [eq $v 2] }`, "a 1 c 3"},
+	})
+}
+
+func TestDict_ValueSemantics(t *testing.T) {
+	interp := NewInterp()
+	out, err := interp.ExecString(`set a [dict create k {x 1}]; set b $a; dict set b k x 2; dict get $a k x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String != "1" {
+		t.Errorf("modifying a copy changed the original: got %q", out.String)
+	}
+}
+
+func TestDict_Errors(t *testing.T) {
+	interp := NewInterp()
+	for _, script := range []string{
+		`dict get {a 1} b`,
+		`dict get {a 1 b} a`,
+		`dict create a`,
+		`dict nosuch`,
+		`dict for {k} {a 1} {}`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+
+	// keys takes an optional pattern
+	_, err := interp.ExecString(`dict keys {a 1} a* extra`)
+	if err == nil || !strings.Contains(err.Error(), "expected 1 or 2 positional args") {
+		t.Errorf("expected arg count error naming 1 or 2, got %v", err)
+	}
+}
@@ -30,13 +30,17 @@ var (
 	ErrExpectedArgType      = Error(errExpectedArgType)
 	ErrExpectedBool         = Error(errExpectedBool)
 	ErrExpectedInt          = Error(errExpectedInt)
+	ErrExpectedNumber       = Error(errExpectedNumber)
+	ErrDivByZero            = Error(errDivByZero)
 	ErrExpectedList         = Error(errExpectedList)
+	ErrExpectedChan         = Error(errExpectedChan)
 	ErrNamedArgMissingValue = Error(errNamedArgMissingValue)
 	ErrCommand              = Error(errCommand)
 	ErrLine                 = Error(errLine)
 	ErrNotImplemented       = Error(errNotImplemented)
 	ErrGoPanic              = Error(errGoPanic)
 	ErrUnsupported          = Error(errUnsupported)
+	ErrInterpClosed         = Error(errInterpClosed)
 )
 
 type Error func(...any) error
@@ -294,6 +298,24 @@ func errExpectedInt(args ...any) error {
 	}
 }
 
+func errExpectedNumber(args ...any) error {
+	switch len(args) {
+	case 1:
+		return fmt.Errorf("%w, got %q", errExpectedNumber(), args[0])
+	default:
+		return adzError("expected number")
+	}
+}
+
+func errDivByZero(args ...any) error {
+	switch len(args) {
+	case 1:
+		return fmt.Errorf("%v: %w", args[0], errDivByZero())
+	default:
+		return adzError("division by zero")
+	}
+}
+
 func errExpectedList(args ...any) error {
 	switch len(args) {
 	case 1:
@@ -303,6 +325,15 @@ func errExpectedList(args ...any) error {
 	}
 }
 
+func errExpectedChan(args ...any) error {
+	switch len(args) {
+	case 1:
+		return fmt.Errorf("%w: %v", errExpectedChan(), args[0])
+	default:
+		return adzError("expected a channel")
+	}
+}
+
 func errNamedArgMissingValue(args ...any) error {
 	switch len(args) {
 	case 1:
@@ -369,3 +400,7 @@ func errUnsupported(args ...any) error {
 		return adzError("unsupported")
 	}
 }
+
+func errInterpClosed(args ...any) error {
+	return adzError("interpreter closed")
+}
@@ -0,0 +1,659 @@
+package adz
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sparques/adz/parser"
+)
+
+func init() {
+	StdLib["expr"] = ProcExpr
+}
+
+// ProcExpr evaluates its arguments, joined by spaces, as an infix
+// expression:
+//
+//	expr {($a + 1) * 2 > [list::len $l] ? "big" : "small"}
+//
+// Operators, loosest binding first, are ?:, ||, &&, |, ^, &, == !=,
+// < <= > >=, << >>, + -, * / %, unary - + ! ~, and **. Arithmetic follows
+// the same int/float promotion as the math procs; comparisons follow
+// Token.Compare, so they're numeric when both sides are numbers. && || and ?: only
+// evaluate the operands they need. Operands are numbers, true and false,
+// $variables, [commands], "strings" (substituted), {strings} (literal) and
+// calls to the functions of the math namespace, such as sqrt($x) or
+// max(1, $y).
+//
+// A single argument's parsed form is cached in its Data, so an expression
+// stored in a variable, or braced in a script, is only parsed once.
+func ProcExpr(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, 0)
+	}
+	var src *Token
+	if len(args) == 2 {
+		src = args[1]
+	} else {
+		src = NewTokenString(TokenJoin(args[1:], " "))
+	}
+	node, err := src.asExpr()
+	if err != nil {
+		return EmptyToken, err
+	}
+	return node.eval(interp)
+}
+
+// asExpr parses tok as an expression, caching the result in Data if Data
+// isn't holding anything else.
+func (tok *Token) asExpr() (exprNode, error) {
+	if node, ok := tok.Data.(exprNode); ok {
+		return node, nil
+	}
+	node, err := parseExpr(tok.String)
+	if err != nil {
+		return nil, err
+	}
+	if tok.Data == nil {
+		tok.Data = node
+	}
+	return node, nil
+}
+
+type exprKind int
+
+const (
+	exprEOF exprKind = iota
+	exprNum
+	exprVar
+	exprCmd
+	exprQuoted
+	exprBraced
+	exprIdent
+	exprOp
+)
+
+type exprLexeme struct {
+	kind exprKind
+	text string
+	pos  int
+}
+
+// exprOps lists the operators, longest first so that "<=" wins over "<".
+var exprOps = []string{
+	"**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "%", "&", "|", "^", "~", "!", "<", ">", "?", ":", "(", ")", ",",
+}
+
+func lexExpr(src string) ([]exprLexeme, error) {
+	var lexemes []exprLexeme
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			for i < len(src) && (isIdentChar(src[i]) || src[i] == '.') {
+				// allow a signed exponent, but not in hex where e is a digit
+				if (src[i] == 'e' || src[i] == 'E') && i+1 < len(src) &&
+					(src[i+1] == '-' || src[i+1] == '+') && !strings.HasPrefix(src[start:], "0x") {
+					i++
+				}
+				i++
+			}
+			lexemes = append(lexemes, exprLexeme{exprNum, src[start:i], start})
+			continue
+		case c == '$':
+			if i+1 == len(src) {
+				return nil, ErrSyntax("expected variable name after $")
+			}
+			i += getVarEndIndex(src[i:])
+			// getVarEndIndex stops at whitespace, not at operators
+			if src[start+1] != '{' {
+				name := start + 1
+				for name < i && (isIdentChar(src[name]) || src[name] == ':') {
+					name++
+				}
+				if name < i && src[name] == '(' {
+					name += parser.FindMate(src[name:], '(', ')') + 1
+				}
+				i = name
+			}
+			lexemes = append(lexemes, exprLexeme{exprVar, src[start:i], start})
+			continue
+		case c == '[':
+			end := parser.FindMate(src[i:], '[', ']')
+			if end == -1 {
+				return nil, ErrSyntax("could not find matching ]")
+			}
+			i += end + 1
+			lexemes = append(lexemes, exprLexeme{exprCmd, src[start+1 : i-1], start})
+			continue
+		case c == '"':
+			end := parser.FindPair(src[i:], '"')
+			if end == -1 {
+				return nil, ErrSyntax("could not find matching \"")
+			}
+			i += end + 1
+			lexemes = append(lexemes, exprLexeme{exprQuoted, src[start+1 : i-1], start})
+			continue
+		case c == '{':
+			end := parser.FindMate(src[i:], '{', '}')
+			if end == -1 {
+				return nil, ErrSyntax("could not find matching }")
+			}
+			i += end + 1
+			lexemes = append(lexemes, exprLexeme{exprBraced, src[start+1 : i-1], start})
+			continue
+		case isIdentChar(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			lexemes = append(lexemes, exprLexeme{exprIdent, src[start:i], start})
+			continue
+		}
+
+		op := ""
+		for _, o := range exprOps {
+			if strings.HasPrefix(src[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, ErrSyntax(fmt.Sprintf("unexpected %q at %d", c, i))
+		}
+		i += len(op)
+		lexemes = append(lexemes, exprLexeme{exprOp, op, start})
+	}
+	return append(lexemes, exprLexeme{exprEOF, "", len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// exprBinaryPrec is the precedence of each binary operator; higher binds
+// tighter. ** is handled separately since it's right associative and binds
+// tighter than unary operators.
+var exprBinaryPrec = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+type exprParser struct {
+	lexemes []exprLexeme
+	pos     int
+}
+
+func parseExpr(src string) (exprNode, error) {
+	lexemes, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{lexemes: lexemes}
+	node, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != exprEOF {
+		return nil, p.unexpected()
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprLexeme {
+	return p.lexemes[p.pos]
+}
+
+func (p *exprParser) next() exprLexeme {
+	lx := p.lexemes[p.pos]
+	if lx.kind != exprEOF {
+		p.pos++
+	}
+	return lx
+}
+
+// isOp reports whether the next lexeme is the operator op.
+func (p *exprParser) isOp(op string) bool {
+	lx := p.peek()
+	return lx.kind == exprOp && lx.text == op
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.isOp(op) {
+		return ErrSyntaxExpected(op, p.describe(p.peek()))
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) describe(lx exprLexeme) string {
+	if lx.kind == exprEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at %d", lx.text, lx.pos)
+}
+
+func (p *exprParser) unexpected() error {
+	return ErrSyntax("unexpected " + p.describe(p.peek()))
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(1)
+	if err != nil || !p.isOp("?") {
+		return cond, err
+	}
+	p.next()
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &exprTernary{cond, then, els}, nil
+}
+
+func (p *exprParser) binary(minPrec int) (exprNode, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		lx := p.peek()
+		prec, ok := exprBinaryPrec[lx.text]
+		if lx.kind != exprOp || !ok || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.binary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		if lx.text == "&&" || lx.text == "||" {
+			lhs = &exprLogic{lx.text, lhs, rhs}
+		} else {
+			lhs = &exprBinary{lx.text, lhs, rhs}
+		}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	lx := p.peek()
+	if lx.kind == exprOp && strings.Contains("-+!~", lx.text) {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{lx.text, x}, nil
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (exprNode, error) {
+	base, err := p.primary()
+	if err != nil || !p.isOp("**") {
+		return base, err
+	}
+	p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &exprBinary{"**", base, exp}, nil
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	lx := p.next()
+	switch lx.kind {
+	case exprNum:
+		n, err := numericFromToken(NewTokenString(lx.text))
+		if err != nil {
+			return nil, ErrSyntax(fmt.Sprintf("bad number %q at %d", lx.text, lx.pos))
+		}
+		return &exprLiteral{n.Token()}, nil
+	case exprVar:
+		return &exprVarRef{lx.text}, nil
+	case exprCmd:
+		return &exprCommand{lx.text}, nil
+	case exprQuoted:
+		return &exprQuotedStr{lx.text}, nil
+	case exprBraced:
+		return &exprLiteral{NewTokenString(lx.text)}, nil
+	case exprIdent:
+		switch lx.text {
+		case "true":
+			return &exprLiteral{TrueToken}, nil
+		case "false":
+			return &exprLiteral{FalseToken}, nil
+		}
+		if !p.isOp("(") {
+			return nil, ErrSyntax(fmt.Sprintf("bare word %q at %d; quote strings or add () to call a function", lx.text, lx.pos))
+		}
+		return p.call(lx)
+	case exprOp:
+		if lx.text == "(" {
+			node, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	if lx.kind != exprEOF {
+		p.pos--
+	}
+	return nil, p.unexpected()
+}
+
+func (p *exprParser) call(name exprLexeme) (exprNode, error) {
+	fn, ok := mathFuncs[name.text]
+	if !ok {
+		return nil, ErrSyntax(fmt.Sprintf("unknown function %q at %d", name.text, name.pos))
+	}
+	p.next() // (
+	call := &exprCall{name: name.text, fn: fn}
+	if p.isOp(")") {
+		p.next()
+		return call, nil
+	}
+	for {
+		arg, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if p.isOp(")") {
+			p.next()
+			return call, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// exprNode is a parsed expression.
+type exprNode interface {
+	eval(interp *Interp) (*Token, error)
+}
+
+type exprLiteral struct {
+	tok *Token
+}
+
+func (e *exprLiteral) eval(*Interp) (*Token, error) {
+	return e.tok, nil
+}
+
+type exprVarRef struct {
+	ref string
+}
+
+func (e *exprVarRef) eval(interp *Interp) (*Token, error) {
+	return interp.lookupVar(e.ref)
+}
+
+type exprCommand struct {
+	script string
+}
+
+func (e *exprCommand) eval(interp *Interp) (*Token, error) {
+	return interp.ExecString(e.script)
+}
+
+type exprQuotedStr struct {
+	str string
+}
+
+func (e *exprQuotedStr) eval(interp *Interp) (*Token, error) {
+	return interp.Subst(NewTokenString(e.str))
+}
+
+type exprTernary struct {
+	cond, then, els exprNode
+}
+
+func (e *exprTernary) eval(interp *Interp) (*Token, error) {
+	b, err := exprBool(interp, e.cond)
+	if err != nil {
+		return EmptyToken, err
+	}
+	if b {
+		return e.then.eval(interp)
+	}
+	return e.els.eval(interp)
+}
+
+type exprLogic struct {
+	op       string
+	lhs, rhs exprNode
+}
+
+func (e *exprLogic) eval(interp *Interp) (*Token, error) {
+	b, err := exprBool(interp, e.lhs)
+	if err != nil {
+		return EmptyToken, err
+	}
+	// short circuit
+	if b == (e.op == "||") {
+		return NewToken(b), nil
+	}
+	b, err = exprBool(interp, e.rhs)
+	if err != nil {
+		return EmptyToken, err
+	}
+	return NewToken(b), nil
+}
+
+type exprUnary struct {
+	op string
+	x  exprNode
+}
+
+func (e *exprUnary) eval(interp *Interp) (*Token, error) {
+	switch e.op {
+	case "!":
+		b, err := exprBool(interp, e.x)
+		if err != nil {
+			return EmptyToken, err
+		}
+		return NewToken(!b), nil
+	case "~":
+		tok, err := e.x.eval(interp)
+		if err != nil {
+			return EmptyToken, err
+		}
+		return ProcBitNot(interp, []*Token{NewTokenString("~"), tok})
+	}
+
+	tok, err := e.x.eval(interp)
+	if err != nil {
+		return EmptyToken, err
+	}
+	n, err := numericFromToken(tok)
+	if err != nil {
+		return EmptyToken, err
+	}
+	if e.op == "-" {
+		n = numericValue{}.Sub(n)
+	}
+	return n.Token(), nil
+}
+
+type exprBinary struct {
+	op       string
+	lhs, rhs exprNode
+}
+
+func (e *exprBinary) eval(interp *Interp) (*Token, error) {
+	a, err := e.lhs.eval(interp)
+	if err != nil {
+		return EmptyToken, err
+	}
+	b, err := e.rhs.eval(interp)
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	switch e.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return NewToken(exprCompare(e.op, a, b)), nil
+	case "%", "&", "|", "^", "<<", ">>":
+		return exprIntOp(e.op, a, b)
+	}
+
+	x, err := numericFromToken(a)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", e.op, err)
+	}
+	y, err := numericFromToken(b)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", e.op, err)
+	}
+	switch e.op {
+	case "+":
+		return x.Add(y).Token(), nil
+	case "-":
+		return x.Sub(y).Token(), nil
+	case "*":
+		return x.Mul(y).Token(), nil
+	case "/":
+		return x.Div(y).Token(), nil
+	case "**":
+		return x.Pow(y).Token(), nil
+	}
+	return EmptyToken, ErrSyntax("unknown operator " + e.op)
+}
+
+// exprCompare compares a and b as the comparison procs do.
+func exprCompare(op string, a, b *Token) bool {
+	switch op {
+	case "==":
+		return a.Equal(b)
+	case "!=":
+		return !a.Equal(b)
+	}
+	c := a.Compare(b)
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func exprIntOp(op string, a, b *Token) (*Token, error) {
+	x, err := integerFromToken(a)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", op, err)
+	}
+	var n numericValue
+	switch op {
+	case "<<", ">>":
+		count, err := strictIntFromToken(b)
+		if err != nil {
+			return EmptyToken, fmt.Errorf("%s: %w", op, err)
+		}
+		n, err = shiftInt(x, count, op == "<<")
+		if err != nil {
+			return EmptyToken, err
+		}
+		return n.Token(), nil
+	}
+
+	y, err := integerFromToken(b)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", op, err)
+	}
+	switch op {
+	case "%":
+		n, err = remInt(x, y, false)
+		if err != nil {
+			return EmptyToken, err
+		}
+	case "&":
+		n = integerOp(x, y, exact(func(a, b int) int { return a & b }), (*big.Int).And)
+	case "|":
+		n = integerOp(x, y, exact(func(a, b int) int { return a | b }), (*big.Int).Or)
+	case "^":
+		n = integerOp(x, y, exact(func(a, b int) int { return a ^ b }), (*big.Int).Xor)
+	}
+	return n.Token(), nil
+}
+
+type exprCall struct {
+	name string
+	fn   mathFunc
+	args []exprNode
+}
+
+func (e *exprCall) eval(interp *Interp) (*Token, error) {
+	args := make([]numericValue, len(e.args))
+	for i, arg := range e.args {
+		tok, err := arg.eval(interp)
+		if err != nil {
+			return EmptyToken, err
+		}
+		args[i], err = numericFromToken(tok)
+		if err != nil {
+			return EmptyToken, fmt.Errorf("%s(): arg %d: %w", e.name, i+1, err)
+		}
+	}
+	n, err := e.fn.call(args)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s(): %w", e.name, err)
+	}
+	return n.Token(), nil
+}
+
+// exprBool evaluates node as a condition. Besides the usual boolean
+// strings, any number is true if it's non-zero.
+func exprBool(interp *Interp, node exprNode) (bool, error) {
+	tok, err := node.eval(interp)
+	if err != nil {
+		return false, err
+	}
+	switch v := tok.Data.(type) {
+	case bool:
+		return v, nil
+	case int, float64, Integer, Floater:
+		n, _ := numericFromToken(tok)
+		return n.Float64() != 0, nil
+	}
+	if b, err := tok.AsBool(); err == nil {
+		return b, nil
+	}
+	if n, err := numericFromToken(tok); err == nil {
+		return n.Float64() != 0, nil
+	}
+	return false, ErrExpectedBool(tok.String)
+}
@@ -0,0 +1,144 @@
+package adz
+
+import "testing"
+
+func TestExpr(t *testing.T) {
+	interp := NewInterp()
+	interp.ExecString(`set a 3; set b 4.5; set l {x y z}; set d [dict create k 10]`)
+
+	runScripts(t, interp, []scriptTest{
+		{`expr {1 + 2 * 3}`, "7"},
+		{`expr {(1 + 2) * 3}`, "9"},
+		{`expr {10 - 4 - 3}`, "3"},
+		{`expr {7 / 2}`, "3.5"},
+		{`expr {7 % 3}`, "1"},
+		{`expr {2 ** 10}`, "1024"},
+		{`expr {2 ** 3 ** 2}`, "512"},
+		{`expr {-2 ** 2}`, "-4"},
+		{`expr {2 ** -1}`, "0.5"},
+		{`expr {-$a + 1}`, "-2"},
+		{`expr {$a + $b}`, "7.5"},
+		{`expr {$a * 2 + 1}`, "7"},
+		{`expr {$a+1}`, "4"},
+		{`expr {$d(k) / 4}`, "2.5"},
+		{`expr {[+ $a 1] * 2}`, "8"},
+		{`expr {[list::len $l]}`, "3"},
+		{`expr {1 < 2 && 2 < 3}`, "true"},
+		{`expr {1 > 2 || !(3 >= 3)}`, "false"},
+		{`expr {!0}`, "true"},
+		{`expr {$a == 3.0}`, "true"},
+		{`expr {$a != 3}`, "false"},
+		{`expr {"abc" < "abd"}`, "true"},
+		{`expr {{a b} == "a b"}`, "true"},
+		{`expr {"$a x" == {3 x}}`, "true"},
+		{`expr {6 & 3 | 8}`, "10"},
+		{`expr {6 ^ 3}`, "5"},
+		{`expr {~0}`, "-1"},
+		{`expr {1 << 4 >> 2}`, "4"},
+		{`expr {1 + 1 << 2}`, "8"},
+		{`expr {$a > 2 ? "big" : "small"}`, "big"},
+		{`expr {$a > 5 ? "big" : $a > 2 ? "medium" : "small"}`, "medium"},
+		{`expr {abs(-5) + abs(-1.5)}`, "6.5"},
+		{`expr {max(1, $a, 2) - min(4, $b)}`, "-1"},
+		{`expr {0x10 + 1}`, "17"},
+		{`expr {1e3 / 10}`, "100"},
+		{`expr {true && 1}`, "true"},
+	})
+}
+
+func TestExpr_ShortCircuit(t *testing.T) {
+	interp := NewInterp()
+	out, err := interp.ExecString(`set n 0
+		expr {false && [incr n]}
+		expr {true || [incr n]}
+		expr {true ? 1 : [incr n]}
+		return $n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String != "0" {
+		t.Errorf("skipped operands were evaluated %s times", out.String)
+	}
+}
+
+func TestExpr_MultipleArgs(t *testing.T) {
+	interp := NewInterp()
+	out, err := interp.ExecString(`set x 5; expr $x * 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String != "10" {
+		t.Errorf("got %q, want 10", out.String)
+	}
+}
+
+func TestExpr_Cached(t *testing.T) {
+	interp := NewInterp()
+	e := NewTokenString("$x + 1")
+	interp.SetVar("e", e)
+	for i, want := range []string{"2", "3"} {
+		interp.SetVar("x", NewTokenInt(i+1))
+		out, err := interp.ExecString(`expr $e`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String != want {
+			t.Errorf("got %q, want %q", out.String, want)
+		}
+		if _, ok := e.Data.(exprNode); !ok {
+			t.Fatalf("expression not cached: Data is %T", e.Data)
+		}
+	}
+}
+
+func TestExpr_CachedBraced(t *testing.T) {
+	interp := NewInterp()
+	script, err := LexString(`expr {$x * 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first exprNode
+	for i, want := range []string{"2", "4", "6"} {
+		interp.SetVar("x", NewTokenInt(i+1))
+		out, err := interp.ExecScript(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String != want {
+			t.Errorf("got %q, want %q", out.String, want)
+		}
+		lit, ok := script[0][1].Data.(*Token)
+		if !ok {
+			t.Fatalf("braced word not cached: Data is %T", script[0][1].Data)
+		}
+		node, ok := lit.Data.(exprNode)
+		if !ok {
+			t.Fatalf("expression not cached: Data is %T", lit.Data)
+		}
+		if first == nil {
+			first = node
+		} else if node != first {
+			t.Errorf("run %d parsed the expression again", i+1)
+		}
+	}
+}
+
+func TestExpr_Errors(t *testing.T) {
+	interp := NewInterp()
+	for _, expr := range []string{
+		`1 +`,
+		`(1 + 2`,
+		`1 2`,
+		`foo`,
+		`nosuch(1)`,
+		`1 ? 2`,
+		`"a" + 1`,
+		`1.5 & 1`,
+		`1 % 0`,
+		`$nosuch + 1`,
+	} {
+		if _, err := interp.ExecString("expr {" + expr + "}"); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package adz
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A field path names a value inside a GoObject using the same dotted keys
+// flatten produces, e.g. Config.Ports.2 or Labels.env. Each segment selects
+// a struct field (by its json name or its Go name), a slice or array
+// element (by index) or a map entry (by key). Pointers and interfaces are
+// followed along the way.
+
+// pathOp is applied to the value a path names. v is always settable. It
+// reports whether it changed v, so that values copied out of maps and
+// interfaces only get stored back when needed.
+type pathOp func(v reflect.Value) (changed bool, err error)
+
+// splitPath splits a field path, with or without its leading dot, into
+// segments.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// applyPath walks segs from v, which must be settable, and applies op to the
+// value at the end. When create is true, missing map entries and nil
+// pointers are filled in with zero values instead of being errors.
+func applyPath(interp *Interp, v reflect.Value, segs []string, create bool, op pathOp) (bool, error) {
+	if len(segs) == 0 {
+		return op(v)
+	}
+
+	// follow pointers and interfaces
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if !create {
+				return false, fmt.Errorf("%s is nil", v.Type())
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		// the dynamic value isn't settable, so work on a copy
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		changed, err := applyPath(interp, elem, segs, create, op)
+		if changed {
+			v.Set(elem)
+		}
+		return changed, err
+	}
+
+	seg, rest := segs[0], segs[1:]
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fi := typeInfoOf(reflect.PointerTo(v.Type())).field(seg)
+		if fi == nil {
+			return false, fmt.Errorf("no such field %q", seg)
+		}
+		return applyPath(interp, v.FieldByIndex(fi.index), rest, create, op)
+
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(seg)
+		if err != nil || i < 0 || i >= v.Len() {
+			return false, fmt.Errorf("index %q out of range [0,%d)", seg, v.Len())
+		}
+		return applyPath(interp, v.Index(i), rest, create, op)
+
+	case reflect.Map:
+		key, err := tokenToValue(interp, NewToken(seg), v.Type().Key())
+		if err != nil {
+			return false, fmt.Errorf("map key %q: %w", seg, err)
+		}
+		// map entries aren't addressable either
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(key); cur.IsValid() {
+			elem.Set(cur)
+		} else if !create {
+			return false, fmt.Errorf("no such key %q", seg)
+		}
+		changed, err := applyPath(interp, elem, rest, create, op)
+		if changed {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return changed, err
+	}
+
+	return false, fmt.Errorf("cannot index %s with %q", v.Type(), seg)
+}
+
+// indirectOp wraps op so that it sees through non-nil pointers and interfaces
+// to the container underneath, as applyPath does for intermediate segments.
+func indirectOp(op pathOp) pathOp {
+	var wrapped pathOp
+	wrapped = func(v reflect.Value) (bool, error) {
+		switch {
+		case v.Kind() == reflect.Pointer && !v.IsNil():
+			return wrapped(v.Elem())
+		case v.Kind() == reflect.Interface && !v.IsNil():
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			changed, err := wrapped(elem)
+			if changed {
+				v.Set(elem)
+			}
+			return changed, err
+		}
+		return op(v)
+	}
+	return wrapped
+}
+
+// getPath returns the value at path.
+func (g *GoObject) getPath(interp *Interp, path string) (*Token, error) {
+	var out *Token
+	_, err := applyPath(interp, g.ptr.Elem(), splitPath(path), false, func(v reflect.Value) (bool, error) {
+		out = wrapReturn(v.Interface())
+		return false, nil
+	})
+	return out, err
+}
+
+// setPath converts val to the type at path and stores it there, creating
+// map entries as needed.
+func (g *GoObject) setPath(interp *Interp, path string, val *Token) (*Token, error) {
+	var out *Token
+	_, err := applyPath(interp, g.ptr.Elem(), splitPath(path), true, func(v reflect.Value) (bool, error) {
+		nv, err := tokenToValue(interp, val, v.Type())
+		if err != nil {
+			return false, err
+		}
+		v.Set(nv)
+		out = wrapReturn(v.Interface())
+		return true, nil
+	})
+	return out, err
+}
+
+// appendPath appends vals to the slice at path.
+func (g *GoObject) appendPath(interp *Interp, path string, vals []*Token) (*Token, error) {
+	var out *Token
+	_, err := applyPath(interp, g.ptr.Elem(), splitPath(path), true, indirectOp(func(v reflect.Value) (bool, error) {
+		if v.Kind() != reflect.Slice {
+			return false, fmt.Errorf("cannot append to %s", v.Type())
+		}
+		s := v
+		for i, val := range vals {
+			nv, err := tokenToValue(interp, val, v.Type().Elem())
+			if err != nil {
+				return false, fmt.Errorf("value %d: %w", i+1, err)
+			}
+			s = reflect.Append(s, nv)
+		}
+		v.Set(s)
+		out = wrapReturn(v.Interface())
+		return true, nil
+	}))
+	return out, err
+}
+
+// deletePath removes the map entry or slice element at path.
+func (g *GoObject) deletePath(interp *Interp, path string) error {
+	segs := splitPath(path)
+	if len(segs) == 0 {
+		return fmt.Errorf("nothing to delete")
+	}
+	last := segs[len(segs)-1]
+	_, err := applyPath(interp, g.ptr.Elem(), segs[:len(segs)-1], false, indirectOp(func(v reflect.Value) (bool, error) {
+		switch v.Kind() {
+		case reflect.Map:
+			key, err := tokenToValue(interp, NewToken(last), v.Type().Key())
+			if err != nil {
+				return false, fmt.Errorf("map key %q: %w", last, err)
+			}
+			if !v.MapIndex(key).IsValid() {
+				return false, fmt.Errorf("no such key %q", last)
+			}
+			v.SetMapIndex(key, reflect.Value{})
+			return true, nil
+		case reflect.Slice:
+			i, err := strconv.Atoi(last)
+			if err != nil || i < 0 || i >= v.Len() {
+				return false, fmt.Errorf("index %q out of range [0,%d)", last, v.Len())
+			}
+			v.Set(reflect.AppendSlice(v.Slice(0, i), v.Slice(i+1, v.Len())))
+			return true, nil
+		}
+		return false, fmt.Errorf("cannot delete from %s", v.Type())
+	}))
+	return err
+}
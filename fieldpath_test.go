@@ -0,0 +1,128 @@
+package adz
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+type pathConfig struct {
+	Ports []int
+	Limit *int
+}
+
+type pathServer struct {
+	Name   string            `json:"name"`
+	Config pathConfig        `json:"config"`
+	Labels map[string]string `json:"labels"`
+	Meta   map[string]any    `json:"meta"`
+	Hosts  map[string]*pathConfig
+}
+
+func newPathServer() *Token {
+	return WrapObject(&pathServer{
+		Name:   "web",
+		Config: pathConfig{Ports: []int{80, 443, 8080}},
+		Labels: map[string]string{"env": "prod"},
+		Meta:   map[string]any{"owner": map[string]any{"team": "infra"}},
+	}, nil)
+}
+
+func TestFieldPath_GetSet(t *testing.T) {
+	interp := NewInterp()
+	interp.SetVar("s", newPathServer())
+
+	runScripts(t, interp, []scriptTest{
+		{`$s .Name`, "web"},
+		{`$s .name`, "web"},
+		{`$s .config.Ports.2`, "8080"},
+		{`$s .Config.Ports.1 8443; $s .Config.Ports.1`, "8443"},
+		{`$s .labels.env`, "prod"},
+		{`$s .Labels.region us-east; $s .Labels.region`, "us-east"},
+		{`$s .meta.owner.team`, "infra"},
+		{`$s .meta.owner.team ops; $s .meta.owner.team`, "ops"},
+	})
+
+	// nil pointers and missing map entries are created on set
+	out, err := interp.ExecString(`$s .Hosts.db.Ports {5432}; $s .Hosts.db.Ports.0`)
+	if err != nil || out.String != "5432" {
+		t.Errorf("expected 5432 from newly created map entry, got %q, %v", out.String, err)
+	}
+
+	for _, script := range []string{
+		`$s .Nope`,
+		`$s .Config.Ports.3`,
+		`$s .Config.Ports.x`,
+		`$s .Labels.nope`,
+		`$s .Name.x`,
+		`$s .Config.Ports.0 notanumber`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestFieldPath_AppendDelete(t *testing.T) {
+	interp := NewInterp()
+	interp.SetVar("s", newPathServer())
+
+	out, err := interp.ExecString(`$s append .Config.Ports 9000 9001`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String != "[80 443 8080 9000 9001]" {
+		t.Errorf("expected ports appended, got %q", out.String)
+	}
+
+	if _, err := interp.ExecString(`$s delete .Config.Ports.0; $s delete .labels.env`); err != nil {
+		t.Fatal(err)
+	}
+	out, _ = interp.ExecString(`$s .Config.Ports`)
+	if out.String != "[443 8080 9000 9001]" {
+		t.Errorf("expected first port deleted, got %q", out.String)
+	}
+	if _, err := interp.ExecString(`$s .Labels.env`); err == nil {
+		t.Errorf("expected env label to be deleted")
+	}
+
+	if _, err := interp.ExecString(`$s delete .meta.owner.team; $s .meta.owner.team`); err == nil {
+		t.Errorf("expected nested map key to be deleted")
+	}
+
+	for _, script := range []string{
+		`$s append .Name x`,
+		`$s append .Config.Ports x`,
+		`$s delete .Name`,
+		`$s delete .Labels.nope`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestFieldPath_MatchesFlatten(t *testing.T) {
+	interp := NewInterp()
+	interp.SetVar("s", newPathServer())
+
+	keys, err := interp.ExecString(`field -keys true -values false $s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := keys.AsList()
+	if len(list) == 0 {
+		t.Fatal("expected flatten to produce keys")
+	}
+	var got []string
+	for _, key := range list {
+		got = append(got, key.String)
+		if _, err := interp.ExecString(`$s .` + key.String); err != nil {
+			t.Errorf("field key %s is not a valid path: %v", key.String, err)
+		}
+	}
+	sort.Strings(got)
+	if joined := strings.Join(got, " "); !strings.Contains(joined, "config.Ports.2") || !strings.Contains(joined, "labels.env") {
+		t.Errorf("unexpected field keys %v", got)
+	}
+}
@@ -32,6 +32,10 @@ func procField(interp *Interp, args []*Token) (*Token, error) {
 	}
 
 	var obj any = bound["obj"].Data
+	if ier, ok := obj.(Interfacer); ok {
+		// e.g. a GoObject; flatten what it wraps, so keys match its field paths
+		obj = ier.Interface()
+	}
 
 	if obj == nil {
 		// what is a sane default? Error? I think for now we'll split on new lines
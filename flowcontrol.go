@@ -1,11 +1,14 @@
 package adz
 
+import "strings"
+
 func init() {
 	StdLib["if"] = ProcIf
 	StdLib["while"] = ProcWhile
 	StdLib["do"] = ProcDoWhile
 	StdLib["for"] = ProcFor
 	StdLib["foreach"] = ProcForEach
+	StdLib["switch"] = ProcSwitch
 	StdLib["break"] = ProcBreak
 	StdLib["return"] = ProcReturn
 	StdLib["continue"] = ProcContinue
@@ -176,7 +179,7 @@ func ProcForEach(interp *Interp, args []*Token) (ret *Token, err error) {
 		return EmptyToken, ErrArgCount(3, len(args)-1)
 	}
 
-	list, err := args[2].AsList()
+	next, err := foreachIterator(interp, args[2])
 	if err != nil {
 		return EmptyToken, err // ErrArg(2) ?
 	}
@@ -188,14 +191,20 @@ func ProcForEach(interp *Interp, args []*Token) (ret *Token, err error) {
 		return EmptyToken, ErrArgMissing("variable list")
 	}
 	ret = EmptyToken
-	for i := 0; i < len(list); i += len(varList) {
+	for {
 		// set vars...
 		for j := range varList {
-			if i+j >= len(list) {
-				interp.SetVar(varList[j].String, EmptyToken)
-				continue
+			val, ok, err := next()
+			if err != nil {
+				return EmptyToken, err
+			}
+			if !ok {
+				if j == 0 {
+					return ret, nil
+				}
+				val = EmptyToken
 			}
-			interp.SetVar(varList[j].String, list[i+j])
+			interp.SetVar(varList[j].String, val)
 		}
 
 		// eval vody
@@ -206,13 +215,44 @@ func ProcForEach(interp *Interp, args []*Token) (ret *Token, err error) {
 			err = nil
 			continue
 		case ErrBreak:
+			err = nil
 			return
 		default:
 			return
 		}
 	}
+}
+
+// foreachIterator returns a function giving each value foreach loops over
+// in turn, and false once there are no more. That's the elements of tok, or
+// if tok carries a coroutine, each value it yields until it returns.
+func foreachIterator(interp *Interp, tok *Token) (func() (*Token, bool, error), error) {
+	if co, ok := tok.Data.(*Coroutine); ok {
+		return func() (*Token, bool, error) {
+			if co.Done() {
+				return nil, false, nil
+			}
+			val, err := co.resumeWith(interp, nil)
+			if err != nil || co.Done() {
+				// the coroutine's result isn't one of the values it generates
+				return nil, false, err
+			}
+			return val, true, nil
+		}, nil
+	}
 
-	return
+	list, err := tok.AsList()
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+	return func() (*Token, bool, error) {
+		if i >= len(list) {
+			return nil, false, nil
+		}
+		i++
+		return list[i-1], true, nil
+	}, nil
 }
 
 // ProcDoWhile
@@ -264,9 +304,10 @@ func ProcDoWhile(interp *Interp, args []*Token) (*Token, error) {
 }
 
 // ProcSwitch
-// switch can optionally have a single argument so that the full statement
-// switch ?-case false? ?-match <exact|glob|regex>? val { }
-// switch ?-case false? ?-match <exact|glob|regex>? val case n {body1} case b
+// switch can take its pattern and body pairs as a single argument
+// switch ?options? val {pattern body ?pattern body ...?}
+// or as separate arguments
+// switch ?options? val pattern body ?pattern body ...?
 /*
 	switch -case false -match glob $var {
 		n* {
@@ -282,6 +323,139 @@ func ProcDoWhile(interp *Interp, args []*Token) (*Token, error) {
 		}
 	}
 */
+// Options are -case bool, -match exact|glob|regex, and for regex, -matchvar
+// and -indexvar, which name variables to set to the list of the matched
+// text and each group, or of their {start end} indices. -- ends the options.
+// A body of - falls through to the next body. A last pattern of default
+// matches anything.
+func ProcSwitch(interp *Interp, args []*Token) (*Token, error) {
+	matchcase, style := true, "exact"
+	var matchVar, indexVar string
+
+	arg := 1
+options:
+	for ; arg < len(args); arg++ {
+		opt := args[arg].String
+		switch opt {
+		case "--":
+			arg++
+			break options
+		case "-case", "-match", "-matchvar", "-indexvar":
+		default:
+			break options
+		}
+		arg++
+		if arg >= len(args) {
+			return EmptyToken, ErrExpectedMore("value", opt)
+		}
+		val := args[arg]
+		switch opt {
+		case "-case":
+			b, err := val.AsBool()
+			if err != nil {
+				return EmptyToken, err
+			}
+			matchcase = b
+		case "-match":
+			switch val.String {
+			case "exact", "glob", "regex":
+				style = val.String
+			default:
+				return EmptyToken, ErrSyntaxExpected("-match", "exact, glob or regex", val.String)
+			}
+		case "-matchvar":
+			matchVar = val.String
+		case "-indexvar":
+			indexVar = val.String
+		}
+	}
+	if (matchVar != "" || indexVar != "") && style != "regex" {
+		return EmptyToken, ErrSyntax("-matchvar and -indexvar require -match regex")
+	}
+
+	if arg >= len(args) {
+		return EmptyToken, ErrExpectedMore("value", "switch")
+	}
+	val := args[arg].String
+	arg++
+
+	branches := args[arg:]
+	if len(branches) == 1 {
+		var err error
+		branches, err = branches[0].AsList()
+		if err != nil {
+			return EmptyToken, ErrExpectedList(args[arg].String)
+		}
+	}
+	if len(branches) == 0 {
+		return EmptyToken, ErrExpectedMore("pattern and body", "value")
+	}
+	if len(branches)%2 != 0 {
+		return EmptyToken, ErrSyntax("extra switch pattern with no body")
+	}
+	if branches[len(branches)-1].String == "-" {
+		return EmptyToken, ErrSyntax("no body for last switch pattern")
+	}
+
+	for i := 0; i < len(branches); i += 2 {
+		pattern := branches[i]
+		var match []int
+		switch {
+		case i == len(branches)-2 && pattern.String == "default":
+			match = []int{}
+		case style == "exact":
+			if val == pattern.String || (!matchcase && strings.EqualFold(val, pattern.String)) {
+				match = []int{}
+			}
+		case style == "glob":
+			matches, err := stringMatcher(style, pattern, matchcase)
+			if err != nil {
+				return EmptyToken, err
+			}
+			if matches(val) {
+				match = []int{}
+			}
+		case style == "regex":
+			re, err := pattern.AsRegexp(!matchcase)
+			if err != nil {
+				return EmptyToken, err
+			}
+			match = re.FindStringSubmatchIndex(val)
+		}
+		if match == nil {
+			continue
+		}
+
+		if err := switchSetMatchVars(interp, val, match, matchVar, indexVar); err != nil {
+			return EmptyToken, err
+		}
+
+		// skip over fallthrough bodies
+		for i+1 < len(branches) && branches[i+1].String == "-" {
+			i += 2
+		}
+		return interp.ExecToken(branches[i+1])
+	}
+
+	return EmptyToken, nil
+}
+
+// switchSetMatchVars sets the -matchvar and -indexvar variables of switch
+// from the submatch indices match of val. The default branch has none, so
+// the variables are set to empty lists.
+func switchSetMatchVars(interp *Interp, val string, match []int, matchVar, indexVar string) error {
+	if matchVar != "" {
+		if _, err := interp.SetVar(matchVar, NewList(submatchTokens(val, match, false))); err != nil {
+			return err
+		}
+	}
+	if indexVar != "" {
+		if _, err := interp.SetVar(indexVar, NewList(submatchTokens(val, match, true))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 // ProcCatch
 func ProcCatch(interp *Interp, args []*Token) (*Token, error) {
@@ -352,6 +526,17 @@ func ProcReturn(interp *Interp, args []*Token) (*Token, error) {
 	return EmptyToken, ErrReturn
 }
 
+// tailcall cmd ?arg ...? ends the current proc, running cmd with the given
+// args in its place; the result is what the proc returns. cmd is resolved
+// before the proc's frame goes away, so it may be a proc local to it.
 func ProcTailcall(interp *Interp, args []*Token) (*Token, error) {
-	return NewList(args), ErrTailcall
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	proc, ok := interp.getProc(args[1])
+	if !ok {
+		return EmptyToken, ErrCommandNotFound(args[1].String)
+	}
+	cmd := append([]*Token{{String: args[1].String, Data: proc}}, args[2:]...)
+	return NewList(cmd), ErrTailcall
 }
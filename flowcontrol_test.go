@@ -0,0 +1,49 @@
+package adz
+
+import (
+	"testing"
+)
+
+func TestSwitch(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`switch b {a {return 1} b {return 2} c {return 3}}`, "2"},
+		{`switch b a {return 1} b {return 2}`, "2"},
+		{`switch z {a {return 1} default {return d}}`, "d"},
+		{`switch z {a {return 1}}`, ""},
+		{`switch default {default {return d}}`, "d"},
+		{`switch a {a - b {return ab} c {return c}}`, "ab"},
+		{`switch b {a - b - c {return abc}}`, "abc"},
+		{`switch B {b {return lower} default {return other}}`, "other"},
+		{`switch -case false B {b {return lower} default {return other}}`, "lower"},
+		{`switch -match glob foo.go {*.c {return c} *.go {return go}}`, "go"},
+		{`switch -match glob -case false FOO.GO {*.go {return go}}`, "go"},
+		{`switch -match regex abc123 {^[a-z]+$ {return word} {\d+$} {return num}}`, "num"},
+		{`switch -match regex -matchvar m -indexvar i {k=v} {{(\w)=(\w)} {return "$m | $i"}}`, "k=v k v | {0 2} {0 0} {2 2}"},
+		{`switch -match regex -matchvar m x {y {} default {return $m}}`, ""},
+		{`switch -- -case {-case {return dash}}`, "dash"},
+		{`set x 2; switch $x [list 1 {return one} 2 {return two}]`, "two"},
+	})
+}
+
+func TestSwitch_FlowControl(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`set acc {}; foreach x {a b c d} { switch $x { b {continue} d {break} }; list::append acc $x }; return $acc`, "a c"},
+		{`proc f {x} { switch $x { a {return early} }; return late }; list [f a] [f b]`, "early late"},
+		{`set n 0; while {< $n 10} { incr n; switch $n { 3 {break} } }; return $n`, "3"},
+	})
+}
+
+func TestSwitch_Errors(t *testing.T) {
+	interp := NewInterp()
+	runScriptErrors(t, interp, []scriptTest{
+		{`switch`, "expected value"},
+		{`switch a`, "expected pattern and body"},
+		{`switch a {a}`, "no body"},
+		{`switch a b {} c`, "no body"},
+		{`switch a {a -}`, "no body for last"},
+		{`switch -match fuzzy a {a {}}`, "exact, glob or regex"},
+		{`switch -matchvar m a {a {}}`, "require -match regex"},
+		{`switch -match regex a {( {}}`, "missing closing )"},
+		{`switch a {a {throw oops}}`, "oops"},
+	})
+}
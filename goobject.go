@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"go/token"
 	"reflect"
 	"strings"
 )
@@ -31,10 +30,13 @@ type GoObject struct {
 	ptr reflect.Value // always *T
 	typ reflect.Type  // T
 
-	methods    map[string]reflect.Value
-	fields     map[string]reflect.StructField
+	info       *typeInfo // shared by every GoObject wrapping a *T
 	methodSigs map[string]*ArgSet
 
+	// documentation for help
+	help      string
+	fieldHelp map[string]string
+
 	// formatting strategy
 	Format            FormatKind
 	StrictJSON        bool
@@ -105,11 +107,19 @@ func WithTextFuncs(m func() ([]byte, error), u func([]byte) error) WrapOption {
 	}
 }
 
+// WithHelp sets the description shown by "$obj help".
+func WithHelp(help string) WrapOption {
+	return func(g *GoObject) { g.help = help }
+}
+
+// WithFieldHelp documents fields by name for "$obj help". Fields can also
+// be documented with a `help:"..."` struct tag.
+func WithFieldHelp(help map[string]string) WrapOption {
+	return func(g *GoObject) { g.fieldHelp = help }
+}
+
 func WrapObject(v any, sigs map[string]*ArgSet, opts ...WrapOption) *Token {
 	goObj := newGoObject(v, sigs)
-	// defaults
-	goObj.Format = DefaultFormat
-	goObj.StrictJSON = DefaultStrictJSON
 	// apply overrides
 	for _, opt := range opts {
 		opt(goObj)
@@ -233,35 +243,14 @@ func newGoObject(v any, methodSigs map[string]*ArgSet) *GoObject {
 			rv = dst
 		}
 	}
-	rt := rv.Elem().Type() // T
-
-	// collect methods on pointer (full set)
-	m := map[string]reflect.Value{}
-	ptrType := rv.Type()
-	for i := 0; i < ptrType.NumMethod(); i++ {
-		mt := ptrType.Method(i)
-		if token.IsExported(mt.Name) {
-			m[mt.Name] = rv.Method(i) // bound method
-		}
-	}
-
-	// collect struct fields (exported only)
-	f := map[string]reflect.StructField{}
-	if rt.Kind() == reflect.Struct {
-		for i := 0; i < rt.NumField(); i++ {
-			sf := rt.Field(i)
-			if sf.PkgPath == "" { // exported
-				f[sf.Name] = sf
-			}
-		}
-	}
 
 	return &GoObject{
 		ptr:        rv,
-		typ:        rt,
-		methods:    m,
-		fields:     f,
+		typ:        rv.Type().Elem(),
+		info:       typeInfoOf(rv.Type()),
 		methodSigs: methodSigs,
+		Format:     DefaultFormat,
+		StrictJSON: DefaultStrictJSON,
 	}
 }
 
@@ -275,48 +264,62 @@ func (g *GoObject) Proc(interp *Interp, args []*Token) (*Token, error) {
 	// 1) conventional help: "$obj help" or "$obj help Method"
 	if name == "help" {
 		if len(args) == 2 {
-			return EmptyToken, nil
-			// return NewTokenString(g.helpOverview()), nil
+			return NewTokenString(g.helpOverview()), nil
 		}
 		target := args[2].String
-		if as, ok := g.methodSigs[target]; ok {
-			return NewTokenString(as.HelpText()), nil
+		if help, ok := g.helpMethod(target); ok {
+			return NewTokenString(help), nil
 		}
-		if _, ok := g.fields[target]; ok {
-			return EmptyToken, nil
-			// return NewTokenString(g.helpField(target)), nil
+		if help, ok := g.helpField(target); ok {
+			return NewTokenString(help), nil
 		}
-		return EmptyToken, ErrCommand(fmt.Sprintf("no such method/field %q", target))
+		return EmptyToken, ErrCommand(args[0].String, fmt.Sprintf("no such method/field %q", target))
 	}
 
-	// 2) dot-prefix means field access: "$obj .Field [newValue?]"
+	// 2) dot-prefix means field access: "$obj .Field.Path [newValue?]"
 	if strings.HasPrefix(name, ".") {
-		field := strings.TrimPrefix(name, ".")
-		sf, ok := g.fields[field]
-		if !ok {
-			return EmptyToken, ErrCommand(fmt.Sprintf("no such field %q", field))
-		}
-		v := g.ptr.Elem().FieldByIndex(sf.Index)
+		var (
+			out *Token
+			err error
+		)
 		switch len(args) {
 		case 2: // get
-			return wrapReturn(v.Interface()), nil
+			out, err = g.getPath(interp, name)
 		case 3: // set
-			newVal, err := coerceTokenTo(interp, args[2], v.Type())
-			if err != nil {
-				return EmptyToken, fmt.Errorf("field %s: %w", field, err)
-			}
-			if !v.CanSet() {
-				return EmptyToken, fmt.Errorf("field %s is not settable", field)
-			}
-			v.Set(reflect.ValueOf(newVal))
-			return wrapReturn(v.Interface()), nil
+			out, err = g.setPath(interp, name, args[2])
 		default:
 			return EmptyToken, ErrArgCount(1, len(args)-2)
 		}
+		if err != nil {
+			return EmptyToken, fmt.Errorf("field %s: %w", name, err)
+		}
+		return out, nil
+	}
+
+	// "$obj append .Slice.Path value ?value ...?" and "$obj delete .Map.key"
+	// lower case names can't collide with exported methods.
+	switch name {
+	case "append":
+		if len(args) < 4 {
+			return EmptyToken, ErrArgMinimum(2, len(args)-2)
+		}
+		out, err := g.appendPath(interp, args[2].String, args[3:])
+		if err != nil {
+			return EmptyToken, fmt.Errorf("field %s: %w", args[2].String, err)
+		}
+		return out, nil
+	case "delete":
+		if len(args) != 3 {
+			return EmptyToken, ErrArgCount(1, len(args)-2)
+		}
+		if err := g.deletePath(interp, args[2].String); err != nil {
+			return EmptyToken, fmt.Errorf("field %s: %w", args[2].String, err)
+		}
+		return EmptyToken, nil
 	}
 
 	// 3) otherwise: method call
-	if meth, ok := g.methods[name]; ok {
+	if mi, ok := g.info.methods[name]; ok {
 		// optional: ArgSet validation if present
 		if as, ok := g.methodSigs[name]; ok {
 			if _, err := as.BindArgs(interp, append([]*Token{args[1]}, args[2:]...)); err != nil {
@@ -324,45 +327,117 @@ func (g *GoObject) Proc(interp *Interp, args []*Token) (*Token, error) {
 				return EmptyToken, err
 			}
 		}
+		return mi.call(interp, g.ptr, args[2:])
+	}
 
-		callArgs := make([]reflect.Value, meth.Type().NumIn())
-		// Start consuming from args[2:]
-		user := args[2:]
-		if len(user) != len(callArgs) {
-			return EmptyToken, ErrArgCount(len(callArgs), len(user))
+	return EmptyToken, ErrCommand(args[0].String, fmt.Sprintf("no such method/field %q", name))
+}
+
+// helpOverview lists the object's methods and fields.
+func (g *GoObject) helpOverview() string {
+	msg := &strings.Builder{}
+	msg.WriteString(g.typ.String())
+	if g.help != "" {
+		fmt.Fprintf(msg, "\n\n%s", g.help)
+	}
+	if len(g.info.methodNames) > 0 {
+		msg.WriteString("\n\nMethods:")
+		for _, name := range g.info.methodNames {
+			fmt.Fprintf(msg, "\n\t%s", g.methodLine(name))
 		}
-		for i := range callArgs {
-			goVal, err := coerceTokenTo(interp, user[i], meth.Type().In(i))
-			if err != nil {
-				return EmptyToken, fmt.Errorf("%s arg %d: %w", name, i+1, err)
-			}
-			callArgs[i] = reflect.ValueOf(goVal)
-		}
-		out := meth.Call(callArgs)
-		// error as last result?
-		if n := len(out); n > 0 {
-			if errT := out[n-1].Type(); errT.Name() == "error" && errT.PkgPath() == "" {
-				if !out[n-1].IsNil() {
-					return EmptyToken, out[n-1].Interface().(error)
-				}
-				out = out[:n-1]
-			}
+	}
+	if len(g.info.fieldNames) > 0 {
+		msg.WriteString("\n\nFields:")
+		for _, name := range g.info.fieldNames {
+			fmt.Fprintf(msg, "\n\t%s", g.fieldLine(g.info.fields[name]))
 		}
-		switch len(out) {
-		case 0:
-			return EmptyToken, nil
-		case 1:
-			return wrapReturn(out[0].Interface()), nil
-		default:
-			toks := make([]*Token, len(out))
-			for i := range out {
-				toks[i] = wrapReturn(out[i].Interface())
-			}
-			return NewList(toks), nil
+	}
+	msg.WriteString("\n")
+	return msg.String()
+}
+
+// helpMethod returns the full help of a method: its ArgSet's, if it has one,
+// or else its Go signature.
+func (g *GoObject) helpMethod(name string) (string, bool) {
+	if as, ok := g.methodSigs[name]; ok {
+		return as.HelpText(), true
+	}
+	if _, ok := g.info.methods[name]; ok {
+		return g.methodLine(name) + "\n", true
+	}
+	return "", false
+}
+
+// helpField returns the help of the field at path, which may have a leading
+// dot and may name a nested field.
+func (g *GoObject) helpField(path string) (string, bool) {
+	segs := splitPath(path)
+	if len(segs) == 0 {
+		return "", false
+	}
+	t := g.typ
+	var fi *fieldInfo
+	for _, seg := range segs {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
 		}
+		if t.Kind() != reflect.Struct {
+			return "", false
+		}
+		if fi = typeInfoOf(reflect.PointerTo(t)).field(seg); fi == nil {
+			return "", false
+		}
+		t = fi.typ
 	}
+	return g.fieldLine(fi) + "\n", true
+}
 
-	return EmptyToken, ErrCommand(fmt.Sprintf("no such method/field %q", name))
+func (g *GoObject) methodLine(name string) string {
+	if as, ok := g.methodSigs[name]; ok {
+		line := as.Signature()
+		if as.Help != "" {
+			line += "\n\t\t" + firstLine(as.Help)
+		}
+		return line
+	}
+	mi := g.info.methods[name]
+	params := make([]string, len(mi.in))
+	for i, t := range mi.in {
+		params[i] = t.String()
+	}
+	if mi.variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	line := strings.TrimSpace(name + " " + strings.Join(params, " "))
+	ft := mi.fn.Type()
+	results := make([]string, 0, ft.NumOut())
+	for i := 0; i < ft.NumOut(); i++ {
+		results = append(results, ft.Out(i).String())
+	}
+	if len(results) > 0 {
+		line += " -> " + strings.Join(results, ", ")
+	}
+	return line
+}
+
+func (g *GoObject) fieldLine(fi *fieldInfo) string {
+	line := "." + fi.name + " " + fi.typ.String()
+	help := g.fieldHelp[fi.name]
+	if help == "" {
+		help = fi.help
+	}
+	if help != "" {
+		line += "\n\t\t" + help
+	}
+	return line
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
 }
 
 func coerceTokenTo(interp *Interp, tok *Token, want reflect.Type) (any, error) {
@@ -375,15 +450,9 @@ func coerceTokenTo(interp *Interp, tok *Token, want reflect.Type) (any, error) {
 		want.Kind() == reflect.Slice || want.Kind() == reflect.Map {
 		if s := strings.TrimSpace(tok.String); s != "" {
 			if norm, kind := InferJSON([]byte(s)); kind != jsonInvalid {
-				dst := reflect.New(want).Interface()
-				if want.Kind() != reflect.Ptr {
-					dst = reflect.New(want).Interface()
-				}
-				if err := json.Unmarshal(norm, dst); err == nil {
-					if want.Kind() == reflect.Ptr {
-						return reflect.ValueOf(dst).Elem().Interface(), nil // want *T, dst is *T
-					}
-					return reflect.ValueOf(dst).Elem().Elem().Interface(), nil // want T
+				dst := reflect.New(want) // *T, or **T when want is *T
+				if err := json.Unmarshal(norm, dst.Interface()); err == nil {
+					return dst.Elem().Interface(), nil
 				}
 			}
 		}
@@ -0,0 +1,38 @@
+package adz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InfoLib holds the subcommands of info, e.g. InfoLib["classes"] implements
+// info classes. Each is called with args[0] set to the subcommand name.
+var InfoLib = map[string]Proc{}
+
+func init() {
+	StdLib["info"] = ProcInfo
+}
+
+// ProcInfo implements info, which introspects the interpreter:
+//
+//	info subcommand ?arg ...?
+func ProcInfo(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, fmt.Errorf("usage: info %s ?arg ...?", strings.Join(infoSubcommands(), "|"))
+	}
+	sub, ok := InfoLib[args[1].String]
+	if !ok {
+		return EmptyToken, ErrCommand("info", fmt.Sprintf("unknown subcommand %q, expected one of %s", args[1].String, strings.Join(infoSubcommands(), ", ")))
+	}
+	return sub(interp, args[1:])
+}
+
+func infoSubcommands() []string {
+	names := make([]string, 0, len(InfoLib))
+	for name := range InfoLib {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
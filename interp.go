@@ -1,11 +1,15 @@
 package adz
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"maps"
+	"math/rand/v2"
+	"slices"
 	"sync"
+	"time"
 )
 
 type Runable interface {
@@ -23,8 +27,46 @@ type Interp struct {
 	calldepth    int
 	MaxCallDepth int
 
+	// classes defined with class define, by qualified name
+	classes map[string]*Class
+
+	// generator for math::rand, seeded from the clock until math::rand::seed
+	// reseeds src
+	rng *rand.Rand
+	src *rand.PCG
+
+	// first token of the command a tailcall trampoline is running; see
+	// Interp.trampoline
+	tailcall *Token
+
+	// the coroutine running, if any
+	coroutine *Coroutine
+	// set on root to the coroutines whose commands haven't returned, by
+	// qualified name
+	coroutines map[string]*Coroutine
+
 	// signal chan Signal
 
+	// running is set on the execution contexts made by enter
+	running bool
+	// the Interp that execution contexts were made from
+	root *Interp
+
+	// children made by interp create, by name
+	children map[string]*Interp
+	// set on children, for calling into their parent
+	link *parentLink
+	// made by NewChild with Safe set
+	safe bool
+	// done when interp is closed; interpreters started by go share the ctx
+	// of the one that started them, and cancel is nil on them. See Close
+	ctx    context.Context
+	cancel context.CancelFunc
+	// set on interpreters started by go to the proc bodies they've lexed,
+	// by source; see procBody.scriptFor
+	scripts map[string]Script
+
+	// Mutex is held while interp runs anything; see enter.
 	*sync.Mutex
 }
 
@@ -91,6 +133,8 @@ func NewInterp() *Interp {
 	nses := make(map[string]*Namespace)
 	nses[""] = globalns
 
+	src := rand.NewPCG(uint64(time.Now().UnixNano()), 0)
+	ctx, cancel := context.WithCancel(context.Background())
 	interp := &Interp{
 		Stdin:      &NilReader{},
 		Stdout:     io.Discard,
@@ -105,14 +149,117 @@ func NewInterp() *Interp {
 		},
 		Monotonic:    make(Monotonic),
 		MaxCallDepth: 1024,
+		classes:      make(map[string]*Class),
+		rng:          rand.New(src),
+		src:          src,
+		children:     make(map[string]*Interp),
+		coroutines:   make(map[string]*Coroutine),
+		ctx:          ctx,
+		cancel:       cancel,
 		Mutex:        &sync.Mutex{},
 	}
+	interp.root = interp
 	// standard library stuff
 	interp.LoadProcs("list", ListLib)
+	interp.LoadProcs("dict", DictLib)
+	interp.LoadProcs("math", MathLib)
+	interp.LoadProcs("math::rand", RandLib)
 	interp.LoadProcs("str", StringsProcs)
+	interp.LoadProcs("chan", ChanLib)
 	return interp
 }
 
+// Close ends everything interp has left running: its coroutines that haven't
+// returned, so that their goroutines exit, its children, closed in turn, and
+// the scripts it started with go, along with any those started, which fail
+// the next time they run a command or wait on a channel. interp, which
+// fails likewise, shouldn't be used afterwards.
+func (interp *Interp) Close() {
+	interp, leave := interp.enter()
+	defer leave()
+	interp.close()
+}
+
+// close is Close for interp, an execution context.
+func (interp *Interp) close() {
+	for name := range interp.root.coroutines {
+		interp.dropCoroutine(name)
+	}
+	for name, child := range interp.children {
+		child.closeFrom(interp)
+		delete(interp.children, name)
+	}
+	if interp.cancel != nil {
+		interp.cancel()
+	}
+}
+
+// closeFrom closes interp, a child of caller's interpreter, while caller,
+// an execution context, waits on it.
+func (interp *Interp) closeFrom(caller *Interp) {
+	interp, leave := interp.enterFrom(caller)
+	defer leave()
+	interp.close()
+}
+
+// closed reports whether interp, or the interpreter that started it with
+// go, has been closed.
+func (interp *Interp) closed() bool {
+	select {
+	case <-interp.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// enter returns the execution context to run a call on interp in, and a
+// function to call once the call is done.
+//
+// An Interp can be used from any number of goroutines, but runs one thing at
+// a time: a call made on it from outside of a running command, such as
+// ExecString from Go code, waits for its lock and then runs in an execution
+// context of its own. That's a shallow copy of interp sharing its
+// namespaces, variables and procs, but with its own Stack, Frame and call
+// depth, which is what commands are passed as their *Interp. Calls made on
+// an execution context, as commands make, are part of the call that's
+// already running, so they go ahead.
+//
+// A command that calls back into the interpreter, as a Go proc does, must
+// do so through the execution context it's passed, not an Interp it kept
+// hold of: a call made through that Interp while it's running waits for the
+// call that's running, which is waiting on it, and so deadlocks. So does
+// waiting, while running a command, on another goroutine that's itself
+// waiting to use the same Interp.
+//
+// An execution context must only be used by the goroutine running it;
+// coroutines are given contexts of their own.
+func (interp *Interp) enter() (*Interp, func()) {
+	if interp.running {
+		return interp, func() {}
+	}
+	interp.Mutex.Lock()
+	return interp.context(), interp.Mutex.Unlock
+}
+
+// enterFrom is enter for a call on interp made while running ctx, an
+// execution context. If they share a lock, as a parent and its children do,
+// it's already held, so it isn't taken again.
+func (interp *Interp) enterFrom(ctx *Interp) (*Interp, func()) {
+	if !interp.running && interp.Mutex == ctx.Mutex {
+		return interp.context(), func() {}
+	}
+	return interp.enter()
+}
+
+// context returns a new execution context for interp; see enter.
+func (interp *Interp) context() *Interp {
+	ctx := *interp
+	ctx.Stack = slices.Clip(interp.Stack)
+	ctx.running = true
+	return &ctx
+}
+
 func (interp *Interp) Push(frame *Frame) {
 	interp.Stack = append(interp.Stack, interp.Frame)
 	interp.Frame = frame
@@ -127,20 +274,28 @@ func (interp *Interp) Pop() {
 }
 
 func (interp *Interp) Proc(name string, proc Proc) (err error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if proc == nil {
 		ns, id, err := interp.ResolveIdentifier(name, false)
 		if err != nil {
 			return err
 		}
 		delete(ns.Procs, id)
+		interp.dropCoroutine(ns.Qualified(id))
 		return nil
 	}
 	ns, id, err := interp.ResolveIdentifier(name, true)
 	ns.Procs[id] = proc
+	interp.dropCoroutine(ns.Qualified(id))
 	return nil
 }
 
 func (interp *Interp) LoadProcs(ns string, procset map[string]Proc) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if _, ok := interp.Namespaces[ns]; !ok {
 		interp.Namespaces[ns] = NewNamespace(ns)
 	}
@@ -148,6 +303,9 @@ func (interp *Interp) LoadProcs(ns string, procset map[string]Proc) {
 }
 
 func (interp *Interp) ResolveProc(name string) (Proc, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	// if it is a fully qualified id, we can skip to a look up
 	if isQualified(name) {
 		proc := interp.AbsoluteProc(name)
@@ -180,6 +338,9 @@ func (interp *Interp) ResolveProc(name string) (Proc, error) {
 // AbsoluteProc exclusively takes a fully qualified path and returns the matching
 // proc if found. Otherwise it returns nil.
 func (interp *Interp) AbsoluteProc(qualPath string) Proc {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if !isQualified(qualPath) {
 		return nil
 	}
@@ -192,6 +353,9 @@ func (interp *Interp) AbsoluteProc(qualPath string) Proc {
 }
 
 func (interp *Interp) GetVar(name string) (v *Token, err error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if isQualified(name) {
 		// already have fully qualified name, just use getVar
 		return interp.getVar(name)
@@ -242,6 +406,9 @@ func (interp *Interp) setVar(qualName string, tok *Token) (*Token, error) {
 }
 
 func (interp *Interp) SetVar(name string, val *Token) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if isQualified(name) {
 		ns, id, err := interp.ResolveIdentifier(name, true)
 		if err != nil {
@@ -273,6 +440,9 @@ func (interp *Interp) SetVar(name string, val *Token) (*Token, error) {
 }
 
 func (interp *Interp) DelVar(name string) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	if isQualified(name) {
 		ns, id, err := interp.ResolveIdentifier(name, true)
 		if err != nil {
@@ -329,10 +499,8 @@ func (interp *Interp) getProc(cmd *Token) (proc Proc, ok bool) {
 // Exec is the main means of running a comand. It does a substitution
 // pass and then calls ExecLiteral().
 func (interp *Interp) Exec(cmd Command) (tok *Token, err error) {
-	if interp.calldepth == 0 {
-		interp.Mutex.Lock()
-		defer interp.Mutex.Unlock()
-	}
+	interp, leave := interp.enter()
+	defer leave()
 
 	interp.calldepth++
 	defer func() {
@@ -346,11 +514,14 @@ func (interp *Interp) Exec(cmd Command) (tok *Token, err error) {
 	if interp.calldepth >= interp.MaxCallDepth {
 		return EmptyToken, ErrMaxCallDepthExceeded
 	}
+	if interp.closed() {
+		return EmptyToken, ErrInterpClosed
+	}
 
 	// substitution pass
 	var args = make([]*Token, len(cmd))
 	for i, tok := range cmd {
-		args[i], err = interp.Subst(tok)
+		args[i], err = interp.substWord(tok)
 		if err != nil {
 			if errors.Is(err, ErrFlowControl) {
 				return EmptyToken, err
@@ -364,6 +535,9 @@ func (interp *Interp) Exec(cmd Command) (tok *Token, err error) {
 
 // ExecLiteral executes cmd without first doing a substitution pass.
 func (interp *Interp) ExecLiteral(cmd Command) (tok *Token, err error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	// get proc
 	proc, found := interp.getProc(cmd[0])
 	if !found {
@@ -387,6 +561,9 @@ func (interp *Interp) ExecLiteral(cmd Command) (tok *Token, err error) {
 }
 
 func (interp *Interp) ExecScript(script Script) (ret *Token, err error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	ret = EmptyToken
 	for line, cmd := range script {
 		ret, err = interp.Exec(cmd)
@@ -402,6 +579,9 @@ func (interp *Interp) ExecScript(script Script) (ret *Token, err error) {
 }
 
 func (interp *Interp) ExecToken(tok *Token) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	// first check if token is already parsed as a Script or Command
 	if len(tok.String) == 0 {
 		return EmptyToken, nil
@@ -421,6 +601,9 @@ func (interp *Interp) ExecToken(tok *Token) (*Token, error) {
 }
 
 func (interp *Interp) ExecBytes(rawScript []byte) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	script, err := LexBytes(rawScript)
 	if err != nil {
 		return EmptyToken, err
@@ -429,6 +612,9 @@ func (interp *Interp) ExecBytes(rawScript []byte) (*Token, error) {
 }
 
 func (interp *Interp) ExecString(str string) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	// attempt to lex str as script
 	script, err := LexString(str)
 	if err != nil {
@@ -438,5 +624,8 @@ func (interp *Interp) ExecString(str string) (*Token, error) {
 }
 
 func (interp *Interp) Printf(format string, args ...any) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	fmt.Fprintf(interp.Stdout, format, args...)
 }
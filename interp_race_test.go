@@ -0,0 +1,208 @@
+package adz
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests are meant to be run with go test -race.
+
+func TestInterp_ConcurrentExec(t *testing.T) {
+	const workers, iterations = 8, 200
+
+	interp := NewInterp()
+	mustRun(t, interp, `set counter 0; proc echo {x} { set y $x; list::append trail $y; return $y }`)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := strconv.Itoa(w)
+			for range iterations {
+				out, err := interp.ExecString(`incr counter; echo ` + id)
+				if err != nil {
+					t.Errorf("worker %d: unexpected error: %v", w, err)
+					return
+				}
+				// each call has its own frames, so its locals are its own
+				if out.String != id {
+					t.Errorf("worker %d: expected %q, got %q", w, id, out.String)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := mustRun(t, interp, `return $counter`); got != strconv.Itoa(workers*iterations) {
+		t.Errorf("expected counter %d, got %s", workers*iterations, got)
+	}
+	if interp.CallDepth() != 0 || len(interp.Stack) != 0 {
+		t.Errorf("expected empty stack, got depth %d and %d frames", interp.CallDepth(), len(interp.Stack))
+	}
+}
+
+func TestInterp_ConcurrentGoAPI(t *testing.T) {
+	const workers, iterations = 8, 100
+
+	interp := NewInterp()
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("::w%d", w)
+			for i := range iterations {
+				if _, err := interp.SetVar(name, NewTokenInt(i)); err != nil {
+					t.Errorf("worker %d: SetVar: %v", w, err)
+					return
+				}
+				val, err := interp.GetVar(name)
+				if err != nil || val.String != strconv.Itoa(i) {
+					t.Errorf("worker %d: GetVar: expected %d, got %v, %v", w, i, val, err)
+					return
+				}
+				interp.Proc(name+"proc", func(*Interp, []*Token) (*Token, error) {
+					return NewTokenInt(w), nil
+				})
+				if _, err := interp.ExecString(fmt.Sprintf(`namespace ns%d { proc p {} {} }; %sproc`, w, name)); err != nil {
+					t.Errorf("worker %d: unexpected error: %v", w, err)
+					return
+				}
+				interp.DelVar(name)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestInterp_ConcurrentCoroutine(t *testing.T) {
+	const workers, iterations = 4, 100
+
+	interp := NewInterp()
+	mustRun(t, interp, `proc count {} { set i 0; while true { yield $i; incr i } }; coroutine next count`)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen []int
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				out, err := interp.ExecString(`next`)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				n, _ := strconv.Atoi(out.String)
+				mu.Lock()
+				seen = append(seen, n)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the first value went to coroutine itself
+	slices.Sort(seen)
+	for i, n := range seen {
+		if n != i+1 {
+			t.Fatalf("expected each value once, got %v", seen)
+		}
+	}
+}
+
+func TestInterp_ConcurrentGo(t *testing.T) {
+	const workers = 4
+
+	interp := NewInterp()
+	mustRun(t, interp, `proc square {x} { * $x $x }`)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := interp.ExecString(`
+				set c [chan::new]
+				foreach i {1 2 3 4 5 6 7 8} { go -vars {c i} { chan::send $c [square $i] } }
+				set sum 0
+				foreach i {1 2 3 4 5 6 7 8} {
+					select { recv $c v { set sum [+ $sum $v] } timeout 10s { throw timeout } }
+				}
+				return $sum`)
+			if err != nil {
+				t.Errorf("worker %d: unexpected error: %v", w, err)
+				return
+			}
+			if out.String != "204" {
+				t.Errorf("worker %d: expected 204, got %q", w, out.String)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestInterp_ReentrantProc(t *testing.T) {
+	root := NewInterp()
+	root.Proc("peek", func(interp *Interp, _ []*Token) (*Token, error) {
+		// the execution context, not root, which is locked by this call
+		return interp.GetVar("x")
+	})
+
+	done := make(chan struct{})
+	var out *Token
+	var err error
+	go func() {
+		defer close(done)
+		out, err = root.ExecString("set x 5; peek")
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlocked calling back into the interpreter from within a proc")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String != "5" {
+		t.Errorf("expected 5, got %q", out.String)
+	}
+}
+
+func TestInterp_GoForeignCommands(t *testing.T) {
+	interp := NewInterp()
+	var stderr strings.Builder
+	interp.Stderr = &stderr
+	mustRun(t, interp, `proc gen {} { while true { yield 1 } }; coroutine g gen
+		class define C { field n 0; method bump {} { my n [+ [my n] 1] } }`)
+	mustRun(t, interp, `set n 0`)
+	clo, err := interp.ExecString(`closure -vars {n} {} { incr n }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interp.Proc("clo", clo.Data.(Proc))
+
+	for _, cmd := range []string{"g", "C new", "clo"} {
+		stderr.Reset()
+		out := mustRun(t, interp, `set c [go {`+cmd+`}]
+			set o [C new]
+			for {set i 0} {< $i 100} {incr i} { g; clo; $o bump }
+			chan::recv $c v`)
+		if out != "false" {
+			t.Errorf("%s: expected go to fail, got %q", cmd, out)
+		}
+		if !strings.Contains(stderr.String(), "belongs to another interpreter") {
+			t.Errorf("%s: expected a foreign call error, got %q", cmd, stderr.String())
+		}
+	}
+}
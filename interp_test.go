@@ -26,6 +26,46 @@ func runErr(t *testing.T, ip *Interp, script string) (string, error) {
 	return "", err
 }
 
+// scriptTest is a script and the result it should give, or for
+// runScriptErrors, text its error should contain.
+type scriptTest struct{ script, want string }
+
+// runScripts runs each script in turn on ip, so later scripts see what
+// earlier ones did, and checks its result. With a nil ip, each script runs
+// on a new interpreter instead.
+func runScripts(t *testing.T, ip *Interp, tests []scriptTest) {
+	t.Helper()
+	for _, tc := range tests {
+		interp := ip
+		if interp == nil {
+			interp = NewInterp()
+		}
+		got, err := runErr(t, interp, tc.script)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.script, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.script, tc.want, got)
+		}
+	}
+}
+
+// runScriptErrors is runScripts for scripts that should fail.
+func runScriptErrors(t *testing.T, ip *Interp, tests []scriptTest) {
+	t.Helper()
+	for _, tc := range tests {
+		interp := ip
+		if interp == nil {
+			interp = NewInterp()
+		}
+		_, err := interp.ExecString(tc.script)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.script, tc.want, err)
+		}
+	}
+}
+
 func TestNamespace_FullyQualifiedVarAlwaysWorks(t *testing.T) {
 	ip := NewInterp()
 	mustRun(t, ip, `set ::a 42`)
@@ -0,0 +1,199 @@
+package adz
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Link is a Getter and Setter that reads and writes a Go variable, so that
+// the variable and the script variable holding the Link are one and the
+// same. Getting it always reflects the Go variable's current value; setting
+// it converts the new value to the variable's type, failing if it can't.
+type Link struct {
+	ptr reflect.Value // pointer to the linked variable
+}
+
+// NewLink returns a Link to the variable ptr points to. The variable must be
+// a bool, a number, a string, a type implementing both
+// encoding.TextMarshaler and encoding.TextUnmarshaler, or a slice of any of
+// those.
+func NewLink(ptr any) (*Link, error) {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, fmt.Errorf("expected a non-nil pointer, got %T", ptr)
+	}
+	if !linkable(rv.Type().Elem()) {
+		return nil, fmt.Errorf("cannot link variable of type %s", rv.Type().Elem())
+	}
+	return &Link{ptr: rv}, nil
+}
+
+// Token returns a token with its .Data set to the link.
+func (l *Link) Token() *Token {
+	tok := valueToken(l.ptr.Elem())
+	return &Token{
+		String: tok.String,
+		Data:   l,
+	}
+}
+
+// Interface returns the pointer to the linked variable.
+func (l *Link) Interface() any {
+	return l.ptr.Interface()
+}
+
+func (l *Link) Get(*Token) (*Token, error) {
+	return valueToken(l.ptr.Elem()), nil
+}
+
+func (l *Link) Set(_, val *Token) (*Token, error) {
+	v := reflect.New(l.ptr.Type().Elem()).Elem()
+	if err := setFromToken(v, val); err != nil {
+		return EmptyToken, err
+	}
+	l.ptr.Elem().Set(v)
+	return valueToken(v), nil
+}
+
+// LinkVar links the script variable name to the Go variable ptr points to;
+// see NewLink for the supported types. A qualified name creates its
+// namespace if needed.
+func (interp *Interp) LinkVar(name string, ptr any) error {
+	interp, leave := interp.enter()
+	defer leave()
+
+	link, err := NewLink(ptr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	interp.bindVar(name, link.Token())
+	return nil
+}
+
+// BindStruct links each exported field of the struct ptr points to as a
+// variable in the namespace ns, so that field Port is ::ns::Port. Fields
+// holding structs become child namespaces, e.g. ::ns::Server::Port. Fields
+// of types LinkVar doesn't support are skipped.
+func (interp *Interp) BindStruct(ns string, ptr any) error {
+	interp, leave := interp.enter()
+	defer leave()
+
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s: expected a pointer to a struct, got %T", ns, ptr)
+	}
+	interp.bindStruct("::"+strings.TrimPrefix(ns, "::"), rv.Elem())
+	return nil
+}
+
+func (interp *Interp) bindStruct(ns string, sv reflect.Value) {
+	for _, name := range typeInfoOf(reflect.PointerTo(sv.Type())).fieldNames {
+		fv := sv.FieldByName(name)
+		switch {
+		case linkable(fv.Type()):
+			interp.bindVar(ns+"::"+name, (&Link{ptr: fv.Addr()}).Token())
+		case fv.Kind() == reflect.Struct:
+			interp.bindStruct(ns+"::"+name, fv)
+		}
+	}
+}
+
+// bindVar stores tok as the variable name without going through SetVar, so
+// that any Setter already there isn't invoked.
+func (interp *Interp) bindVar(name string, tok *Token) {
+	if !isQualified(name) {
+		interp.Frame.localVars[name] = tok
+		return
+	}
+	ns, id, _ := interp.ResolveIdentifier(name, true)
+	ns.Vars[id] = tok
+}
+
+func linkable(t reflect.Type) bool {
+	if t.Implements(textMarshalerType) && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return linkable(t.Elem())
+	}
+	return false
+}
+
+// valueToken returns a token for v, a value of a linkable type.
+func valueToken(v reflect.Value) *Token {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textMarshalerType) {
+		list := make([]*Token, v.Len())
+		for i := range list {
+			list[i] = valueToken(v.Index(i))
+		}
+		return NewList(list)
+	}
+	return NewToken(v.Interface())
+}
+
+// setFromToken parses tok's string into v, which must be settable and of a
+// linkable type. Unlike convertTokenTo, it never takes tok's Data as is, so
+// a value that doesn't fit v's type is always an error.
+func setFromToken(v reflect.Value, tok *Token) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(tok.String))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(tok.String)
+	case reflect.Bool:
+		b, err := tok.AsBool()
+		if err != nil {
+			return ErrExpectedBool(tok.String)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := parseNumber(tok.String)
+		if !ok || n.isFloat || !n.bigInt().IsInt64() || v.OverflowInt(n.bigInt().Int64()) {
+			return fmt.Errorf("expected %s, got %q", v.Type(), tok.String)
+		}
+		v.SetInt(n.bigInt().Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := parseNumber(tok.String)
+		if !ok || n.isFloat || !n.bigInt().IsUint64() || v.OverflowUint(n.bigInt().Uint64()) {
+			return fmt.Errorf("expected %s, got %q", v.Type(), tok.String)
+		}
+		v.SetUint(n.bigInt().Uint64())
+	case reflect.Float32, reflect.Float64:
+		n, ok := parseNumber(tok.String)
+		if !ok || math.IsInf(n.Float64(), 0) || v.OverflowFloat(n.Float64()) {
+			return fmt.Errorf("expected %s, got %q", v.Type(), tok.String)
+		}
+		v.SetFloat(n.Float64())
+	case reflect.Slice:
+		list, err := tok.AsList()
+		if err != nil {
+			return ErrExpectedList(tok.String)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i := range list {
+			if err := setFromToken(slice.Index(i), list[i]); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("cannot set variable of type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package adz
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestLinkVar(t *testing.T) {
+	interp := NewInterp()
+
+	var (
+		count = 1
+		ratio = 0.5
+		debug bool
+		name  = "adz"
+		ports = []uint16{80, 443}
+		addr  = netip.MustParseAddr("127.0.0.1")
+	)
+	for n, ptr := range map[string]any{
+		"count": &count, "ratio": &ratio, "debug": &debug,
+		"name": &name, "ports": &ports, "addr": &addr,
+	} {
+		if err := interp.LinkVar(n, ptr); err != nil {
+			t.Fatalf("LinkVar(%s): %v", n, err)
+		}
+	}
+
+	// Go writes show up in the script
+	count = 42
+	out, err := interp.ExecString(`set x $count`)
+	if err != nil || out.String != "42" {
+		t.Errorf("expected 42, got %q, %v", out.String, err)
+	}
+
+	// script writes show up in Go
+	_, err = interp.ExecString(`set count 7; set ratio 2.25; set debug true; set name hello; set ports {22 8080 0x10}; set addr ::1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 7 || ratio != 2.25 || !debug || name != "hello" || addr != netip.IPv6Loopback() {
+		t.Errorf("unexpected values after set: %v %v %v %q %v", count, ratio, debug, name, addr)
+	}
+	if !reflect.DeepEqual(ports, []uint16{22, 8080, 16}) {
+		t.Errorf("expected ports [22 8080 16], got %v", ports)
+	}
+
+	// type checking leaves the Go variable alone on error
+	for _, script := range []string{
+		`set count abc`,
+		`set count 1.5`,
+		`set ratio x`,
+		`set debug maybe`,
+		`set ports {1 70000}`,
+		`set ports {1 -2}`,
+		`set addr nope`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected type error", script)
+		}
+	}
+	if count != 7 || !reflect.DeepEqual(ports, []uint16{22, 8080, 16}) || addr != netip.IPv6Loopback() {
+		t.Errorf("failed sets changed values: %v %v %v", count, ports, addr)
+	}
+
+	// numbers read as they do everywhere else: decimal unless prefixed
+	_, err = interp.ExecString(`set count 010; set ports {0o10 0b11 1_000}; set ratio 1e3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 || ratio != 1000 || !reflect.DeepEqual(ports, []uint16{8, 3, 1000}) {
+		t.Errorf("unexpected values after set: %v %v %v", count, ratio, ports)
+	}
+
+	var m map[string]int
+	if err := interp.LinkVar("m", &m); err == nil {
+		t.Errorf("expected error linking a map")
+	}
+	if err := interp.LinkVar("n", count); err == nil {
+		t.Errorf("expected error linking a non-pointer")
+	}
+}
+
+func TestBindStruct(t *testing.T) {
+	type server struct {
+		Host string
+		Port int
+	}
+	cfg := struct {
+		Name    string
+		Verbose bool
+		Server  server
+		Hooks   map[string]string // skipped
+		private int
+	}{Name: "svc", Server: server{Host: "localhost", Port: 80}}
+
+	interp := NewInterp()
+	if err := interp.BindStruct("cfg", &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := interp.ExecString(`set port $::cfg::Server::Port`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String != "80" {
+		t.Errorf("expected 80, got %q", out.String)
+	}
+
+	_, err = interp.ExecString(`set ::cfg::Server::Port 8080; set ::cfg::Verbose yes; set ::cfg::Name other`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 8080 || !cfg.Verbose || cfg.Name != "other" {
+		t.Errorf("unexpected cfg after set: %+v", cfg)
+	}
+	if _, err := interp.ExecString(`set ::cfg::Server::Port eighty`); err == nil {
+		t.Errorf("expected type error")
+	}
+
+	if err := interp.BindStruct("x", cfg); err == nil {
+		t.Errorf("expected error binding a non-pointer")
+	}
+}
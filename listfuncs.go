@@ -0,0 +1,535 @@
+package adz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+func init() {
+	ListLib["filter"] = ProcListFilter
+	ListLib["reduce"] = ProcListReduce
+	ListLib["groupby"] = ProcListGroupBy
+	ListLib["any"] = ProcListAny
+	ListLib["all"] = ProcListAll
+	ListLib["zip"] = ProcListZip
+	ListLib["range"] = ProcListRange
+	ListLib["repeat"] = ProcListRepeat
+	ListLib["insert"] = ProcListInsert
+	ListLib["remove"] = ProcListRemove
+	ListLib["replace"] = ProcListReplace
+	ListLib["flatten"] = ProcListFlatten
+	ListLib["join"] = ProcListJoin
+	ListLib["chunk"] = ProcListChunk
+	ListLib["index-of"] = ProcListIndexOf
+}
+
+// listIterate calls cmd with each element of list appended and passes fn
+// the element and the result. As with list::map, elements for which cmd used
+// continue are skipped and break stops early. Any other error stops with
+// that error, unless skipErrors is set, in which case the element is
+// skipped. fn may return ErrBreak to stop early too.
+func listIterate(interp *Interp, list []*Token, cmd Command, skipErrors bool, fn func(elem, ret *Token) error) error {
+	for _, e := range list {
+		// use ExecLiteral so the elements of the list don't get re-interpretted
+		ret, err := interp.ExecLiteral(slices.Concat(cmd, Command{e}))
+		switch {
+		case err == nil:
+			if err := fn(e, ret); err != nil {
+				if errors.Is(err, ErrBreak) {
+					return nil
+				}
+				return err
+			}
+		case errors.Is(err, ErrContinue):
+			// skip this element
+		case errors.Is(err, ErrBreak):
+			return nil
+		case skipErrors:
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// listProcArgSet returns the ArgSet for list commands of the form
+// cmd ?-skiperrors bool? list proc, followed by any extra arguments.
+func listProcArgSet(cmd, help string, extra ...*Argument) *ArgSet {
+	as := NewArgSet(cmd, append([]*Argument{
+		{
+			Name:    "-skiperrors",
+			Help:    "If true, any errors encountered while calling {proc} are simply skipped (as though continue were called) rather than stopping with an error.",
+			Default: FalseToken,
+			Coerce:  Proc(ProcBool).AsToken("bool"),
+		},
+		ArgHelp("list", "The list to iterate over."),
+		ArgHelp("proc", "The proc to call for each list element."),
+	}, extra...)...)
+	as.Help = help
+	return as
+}
+
+// bindListProc binds args with as, returning the list, the proc as a command
+// prefix and whether to skip errors.
+func bindListProc(interp *Interp, as *ArgSet, args []*Token) ([]*Token, Command, bool, error) {
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return nil, nil, false, err
+	}
+	list, err := parsedArgs["list"].AsList()
+	if err != nil {
+		return nil, nil, false, ErrExpectedList(parsedArgs["list"].String)
+	}
+	cmd, _ := parsedArgs["proc"].AsCommand()
+	return list, cmd, parsedArgs["skiperrors"].Data.(bool), nil
+}
+
+func ProcListFilter(interp *Interp, args []*Token) (*Token, error) {
+	as := listProcArgSet(args[0].String, "Returns the elements of {list} for which calling {proc} with the element appended returns true. "+
+		"Elements for which {proc} uses [continue] are left out and [break] ends the list at that element.")
+	list, cmd, skipErrors, err := bindListProc(interp, as, args)
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	out := make([]*Token, 0, len(list))
+	err = listIterate(interp, list, cmd, skipErrors, func(e, ret *Token) error {
+		keep, err := ret.AsBool()
+		if err != nil {
+			return err
+		}
+		if keep {
+			out = append(out, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	return NewList(out), nil
+}
+
+// noInitial is the default for list::reduce's initial argument, telling
+// it apart from an empty initial value.
+var noInitial = &Token{}
+
+func ProcListReduce(interp *Interp, args []*Token) (*Token, error) {
+	as := listProcArgSet(args[0].String, "Calls {proc} with the accumulated value and each element of {list} appended, the result becoming the new accumulated value, and returns the final one. "+
+		"If {proc} uses [continue], the element is skipped; if it uses [break], the value accumulated so far is returned.",
+		&Argument{
+			Name:    "initial",
+			Help:    "The starting value. If not given, the first element of {list} is used.",
+			Default: noInitial,
+		},
+	)
+
+	parsedArgs, err := as.BindArgs(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+	list, err := parsedArgs["list"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["list"].String)
+	}
+	cmdPrefix, _ := parsedArgs["proc"].AsCommand()
+
+	acc := parsedArgs["initial"]
+	if acc == noInitial {
+		if len(list) == 0 {
+			return EmptyToken, ErrCommand(args[0].String, "empty list and no initial value")
+		}
+		acc, list = list[0], list[1:]
+	}
+
+	for _, e := range list {
+		ret, err := interp.ExecLiteral(slices.Concat(cmdPrefix, Command{acc, e}))
+		switch {
+		case err == nil:
+			acc = ret
+		case errors.Is(err, ErrContinue):
+		case errors.Is(err, ErrBreak):
+			return acc, nil
+		case parsedArgs["skiperrors"].Data.(bool):
+		default:
+			return EmptyToken, err
+		}
+	}
+
+	return acc, nil
+}
+
+func ProcListGroupBy(interp *Interp, args []*Token) (*Token, error) {
+	as := listProcArgSet(args[0].String, "Returns a dict whose keys are the results of calling {proc} with each element of {list} appended, "+
+		"each holding the list of elements that gave that key, in order. "+
+		"Elements for which {proc} uses [continue] are left out and [break] stops at that element.")
+	list, cmd, skipErrors, err := bindListProc(interp, as, args)
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	var keys []string
+	groups := make(map[string][]*Token)
+	err = listIterate(interp, list, cmd, skipErrors, func(e, ret *Token) error {
+		if _, ok := groups[ret.String]; !ok {
+			keys = append(keys, ret.String)
+		}
+		groups[ret.String] = append(groups[ret.String], e)
+		return nil
+	})
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	d := NewDict()
+	for _, k := range keys {
+		d.Set(k, NewList(groups[k]))
+	}
+	return d.Token(), nil
+}
+
+func ProcListAny(interp *Interp, args []*Token) (*Token, error) {
+	as := listProcArgSet(args[0].String, "Returns true if calling {proc} with an element of {list} appended returns true, stopping at the first such element. "+
+		"Elements for which {proc} uses [continue] are skipped and [break] stops, returning false.")
+	return listQuantify(interp, as, args, true)
+}
+
+func ProcListAll(interp *Interp, args []*Token) (*Token, error) {
+	as := listProcArgSet(args[0].String, "Returns true unless calling {proc} with an element of {list} appended returns false, stopping at the first such element. "+
+		"Elements for which {proc} uses [continue] are skipped and [break] stops, returning true.")
+	return listQuantify(interp, as, args, false)
+}
+
+// listQuantify implements list::any, which looks for a true result, and
+// list::all, which looks for a false one.
+func listQuantify(interp *Interp, as *ArgSet, args []*Token, want bool) (*Token, error) {
+	list, cmd, skipErrors, err := bindListProc(interp, as, args)
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	found := false
+	err = listIterate(interp, list, cmd, skipErrors, func(_, ret *Token) error {
+		b, err := ret.AsBool()
+		if err != nil {
+			return err
+		}
+		if b == want {
+			found = true
+			return ErrBreak
+		}
+		return nil
+	})
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	if found == want {
+		return TrueToken, nil
+	}
+	return FalseToken, nil
+}
+
+// list::zip list ?list ...? returns a list of lists, the first holding the
+// first element of each list, the second the second elements and so on. It's
+// as long as the shortest list.
+func ProcListZip(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+
+	lists := make([][]*Token, len(args)-1)
+	n := math.MaxInt
+	for i, arg := range args[1:] {
+		list, err := arg.AsList()
+		if err != nil {
+			return EmptyToken, ErrExpectedList(arg.String)
+		}
+		lists[i] = list
+		n = min(n, len(list))
+	}
+
+	out := make([]*Token, n)
+	for i := range out {
+		tuple := make([]*Token, len(lists))
+		for j := range lists {
+			tuple[j] = lists[j][i]
+		}
+		out[i] = NewList(tuple)
+	}
+
+	return NewList(out), nil
+}
+
+// list::range start end ?step? returns the numbers from start to end,
+// inclusive, counting by step. step defaults to 1, or -1 if end is less than
+// start. If any argument is a float, so are the results.
+func ProcListRange(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return EmptyToken, ErrArgCount(3, len(args)-1)
+	}
+
+	var nums [3]numericValue
+	isFloat := false
+	for i, arg := range args[1:] {
+		n, err := numericFromToken(arg)
+		if err != nil {
+			return EmptyToken, err
+		}
+		if n.big != nil {
+			return EmptyToken, ErrCommand(args[0].String, fmt.Sprintf("%s is too large", arg.String))
+		}
+		nums[i] = n
+		isFloat = isFloat || n.isFloat
+	}
+	start, end, step := nums[0], nums[1], nums[2]
+	if len(args) == 3 {
+		step = intValue(1)
+		if lessThan(end, start) {
+			step = intValue(-1)
+		}
+	}
+
+	if !isFloat {
+		if step.i == 0 {
+			return EmptyToken, ErrCommand(args[0].String, "step must not be zero")
+		}
+		out := []*Token{}
+		for i := start.i; (step.i > 0 && i <= end.i) || (step.i < 0 && i >= end.i); i += step.i {
+			out = append(out, NewTokenInt(i))
+			if (step.i > 0 && i > math.MaxInt-step.i) || (step.i < 0 && i < math.MinInt-step.i) {
+				break
+			}
+		}
+		return NewList(out), nil
+	}
+
+	first, last, by := start.Float64(), end.Float64(), step.Float64()
+	if by == 0 || math.IsNaN(by) || math.IsInf(by, 0) {
+		return EmptyToken, ErrCommand(args[0].String, "step must be a finite, non-zero number")
+	}
+	// allow for rounding error, so 0 to 1 by 0.1 has 11 elements
+	count := math.Floor((last-first)/by+1e-9) + 1
+	if math.IsNaN(count) || count < 0 {
+		count = 0
+	}
+	out := make([]*Token, 0, int(count))
+	for i := 0; i < int(count); i++ {
+		out = append(out, floatValue(first+float64(i)*by).Token())
+	}
+	return NewList(out), nil
+}
+
+// list::repeat count ?element ...? returns a list of the elements repeated
+// count times.
+func ProcListRepeat(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	count, err := strictIntFromToken(args[1])
+	if err != nil {
+		return EmptyToken, err
+	}
+	if count < 0 {
+		return EmptyToken, ErrCommand(args[0].String, "count must not be negative")
+	}
+
+	return NewList(slices.Repeat(args[2:], count)), nil
+}
+
+// listIndex resolves idx, which counts from the end of a list of length n if
+// negative.
+func listIndex(idx, n int) int {
+	if idx < 0 {
+		return n + idx
+	}
+	return idx
+}
+
+// list::insert list index ?element ...? returns list with the elements
+// inserted before the element at index. A negative index counts from the
+// end, -1 being after the last element; out of range indices insert at the
+// start or end.
+func ProcListInsert(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 3 {
+		return EmptyToken, ErrArgMinimum(2, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	idx, err := strictIntFromToken(args[2])
+	if err != nil {
+		return EmptyToken, err
+	}
+
+	idx = min(max(listIndex(idx, len(list)+1), 0), len(list))
+	return NewList(slices.Insert(slices.Clone(list), idx, args[3:]...)), nil
+}
+
+// list::remove list ?index ...? returns list without the elements at the
+// given indices. Negative indices count from the end; out of range indices
+// are ignored.
+func ProcListRemove(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 2 {
+		return EmptyToken, ErrArgMinimum(1, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+
+	remove := make([]bool, len(list))
+	for _, arg := range args[2:] {
+		idx, err := strictIntFromToken(arg)
+		if err != nil {
+			return EmptyToken, err
+		}
+		if idx = listIndex(idx, len(list)); idx >= 0 && idx < len(list) {
+			remove[idx] = true
+		}
+	}
+
+	out := make([]*Token, 0, len(list))
+	for i, e := range list {
+		if !remove[i] {
+			out = append(out, e)
+		}
+	}
+	return NewList(out), nil
+}
+
+// list::replace list first last ?element ...? returns list with the elements
+// first through last, inclusive, replaced by the given elements. Negative
+// indices count from the end. If last is before first, nothing is removed
+// and the elements are inserted before first.
+func ProcListReplace(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) < 4 {
+		return EmptyToken, ErrArgMinimum(3, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	var bounds [2]int
+	for i, arg := range args[2:4] {
+		idx, err := strictIntFromToken(arg)
+		if err != nil {
+			return EmptyToken, err
+		}
+		bounds[i] = listIndex(idx, len(list))
+	}
+
+	first := min(max(bounds[0], 0), len(list))
+	last := min(max(bounds[1]+1, first), len(list))
+	return NewList(slices.Replace(slices.Clone(list), first, last, args[4:]...)), nil
+}
+
+// list::flatten list ?depth? returns list with elements that are themselves
+// lists replaced by their elements, depth levels deep. depth defaults to 1;
+// a negative depth flattens completely.
+func ProcListFlatten(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	depth := 1
+	if len(args) == 3 {
+		if depth, err = strictIntFromToken(args[2]); err != nil {
+			return EmptyToken, err
+		}
+	}
+
+	return NewList(flattenList(list, depth)), nil
+}
+
+func flattenList(list []*Token, depth int) []*Token {
+	if depth == 0 {
+		return list
+	}
+	out := make([]*Token, 0, len(list))
+	for _, e := range list {
+		sub, err := e.AsList()
+		if err != nil || (len(sub) == 1 && sub[0].String == e.String) {
+			// not a list, or a single word that can't be split further
+			out = append(out, e)
+			continue
+		}
+		out = append(out, flattenList(sub, depth-1)...)
+	}
+	return out
+}
+
+// list::join list ?separator? returns the elements of list joined by
+// separator, a space by default.
+func ProcListJoin(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	sep := " "
+	if len(args) == 3 {
+		sep = args[2].String
+	}
+
+	strs := make([]string, len(list))
+	for i, e := range list {
+		strs[i] = e.String
+	}
+	return NewTokenString(strings.Join(strs, sep)), nil
+}
+
+// list::chunk list size returns list split into lists of size elements. The
+// last may be shorter.
+func ProcListChunk(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	size, err := strictIntFromToken(args[2])
+	if err != nil {
+		return EmptyToken, err
+	}
+	if size < 1 {
+		return EmptyToken, ErrCommand(args[0].String, "size must be at least 1")
+	}
+
+	out := make([]*Token, 0, (len(list)+size-1)/size)
+	for chunk := range slices.Chunk(list, size) {
+		out = append(out, NewList(chunk))
+	}
+	return NewList(out), nil
+}
+
+// list::index-of ?options? list value returns the index of the first element
+// of list equal to value, or -1 if there isn't one. Options -nocase, -ascii,
+// -numeric and -dictionary select the comparison; see Collation.
+func ProcListIndexOf(interp *Interp, args []*Token) (*Token, error) {
+	coll, args := parseCompareFlags(args)
+	if len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+
+	idx := slices.IndexFunc(list, func(e *Token) bool {
+		return coll.Equal(e, args[2])
+	})
+	return NewTokenInt(idx), nil
+}
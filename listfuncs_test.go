@@ -0,0 +1,83 @@
+package adz
+
+import (
+	"testing"
+)
+
+func TestListFuncs(t *testing.T) {
+	interp := NewInterp()
+	_, err := interp.ExecString(`
+		proc even {x} { expr {$x % 2 == 0} }
+		proc add {a b} { expr {$a + $b} }
+		proc parity {x} { if {even $x} { return even }; return odd }
+		proc stopAt3 {x} { if {== $x 3} { break }; expr {$x % 2 == 0} }
+		proc skip2 {x} { if {== $x 2} { continue }; return $x }
+		proc fail {args} { throw oops }
+		proc addSkip2 {a b} { if {== $b 2} { continue }; + $a $b }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runScripts(t, interp, []scriptTest{
+		{`list::map {1 2 3} skip2`, "1 3"},
+		{`list::filter {1 2 3 4} even`, "2 4"},
+		{`list::filter {2 4 3 6} stopAt3`, "2 4"},
+		{`list::filter -skiperrors true {1 2} fail`, ""},
+		{`list::reduce {1 2 3 4} add`, "10"},
+		{`list::reduce {1 2 3} add 10`, "16"},
+		{`list::reduce {} add {}`, ""},
+		{`list::reduce {1 2 3} addSkip2 0`, "4"},
+		{`list::groupby {1 2 3 4 5} parity`, "odd {1 3 5} even {2 4}"},
+		{`list::any {1 3 4} even`, "true"},
+		{`list::any {1 3 5} even`, "false"},
+		{`list::any {} even`, "false"},
+		{`list::all {2 4 6} even`, "true"},
+		{`list::all {2 3 6} even`, "false"},
+		{`list::all {} even`, "true"},
+		{`list::all {2 4 3 5} stopAt3`, "true"},
+		{`list::zip {a b c} {1 2 3 4}`, "{a 1} {b 2} {c 3}"},
+		{`list::zip {a b}`, "a b"},
+		{`list::range 1 5`, "1 2 3 4 5"},
+		{`list::range 5 1`, "5 4 3 2 1"},
+		{`list::range 0 10 3`, "0 3 6 9"},
+		{`list::range 0 10 -1`, ""},
+		{`list::range 0 1 0.25`, "0 0.25 0.5 0.75 1"},
+		{`list::repeat 3 a b`, "a b a b a b"},
+		{`list::repeat 0 a`, ""},
+		{`list::insert {a b c} 1 x y`, "a x y b c"},
+		{`list::insert {a b c} -1 x`, "a b c x"},
+		{`list::insert {a b c} 99 x`, "a b c x"},
+		{`list::remove {a b c d} 0 -1`, "b c"},
+		{`list::remove {a b} 5`, "a b"},
+		{`list::replace {a b c d} 1 2 x`, "a x d"},
+		{`list::replace {a b c d} 1 0 x`, "a x b c d"},
+		{`list::replace {a b c d} -2 -1`, "a b"},
+		{`list::flatten {a {b {c d}} e}`, "a b {c d} e"},
+		{`list::flatten {a {b {c {d}}}} -1`, "a b c d"},
+		{`list::flatten {a {b c}} 0`, "a {b c}"},
+		{`list::join {a b c}`, "a b c"},
+		{`list::join {a b c} ,`, "a,b,c"},
+		{`list::chunk {a b c d e} 2`, "{a b} {c d} e"},
+		{`list::index-of {a b c} c`, "2"},
+		{`list::index-of {1 2 3} 2.0`, "1"},
+		{`list::index-of -ascii {1 2 3} 2.0`, "-1"},
+		{`list::index-of -nocase {a B c} b`, "1"},
+	})
+}
+
+func TestListFuncs_Errors(t *testing.T) {
+	interp := NewInterp()
+	interp.ExecString(`proc fail {args} { throw oops }; proc id {x} { return $x }`)
+	runScriptErrors(t, interp, []scriptTest{
+		{`list::filter {1 2} fail`, "oops"},
+		{`list::filter {a b} id`, "bool"},
+		{`list::reduce {} fail`, "no initial value"},
+		{`list::any {1} fail`, "oops"},
+		{`list::range 1 5 0`, "must not be zero"},
+		{`list::range a 5`, "expected number"},
+		{`list::repeat -1 a`, "must not be negative"},
+		{`list::chunk {a b} 0`, "at least 1"},
+		{`list::insert {a b} x c`, "expected integer"},
+	})
+}
@@ -3,9 +3,7 @@ package adz
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
 	"slices"
-	"sort"
 	"strings"
 )
 
@@ -224,20 +222,181 @@ func ProcListSet(interp *Interp, args []*Token) (*Token, error) {
 	return interp.SetVar(listName, acc)
 }
 
-// TODO: add various options like -type <numeric> and -order <reverse>
+// list::sort ?options? list sorts list, keeping equal elements in order.
+// Options -nocase, -ascii, -numeric and -dictionary select the comparison;
+// see Collation.
 func ProcSort(interp *Interp, args []*Token) (*Token, error) {
-	if len(args) != 2 {
-		return EmptyToken, ErrArgCount(1, len(args)-1)
-	}
+	as := NewArgSet(args[0].String,
+		&Argument{Name: "-decreasing", Switch: true, Help: "Sort largest first."},
+		&Argument{Name: "-ascii", Switch: true, Help: "Compare elements as strings."},
+		&Argument{Name: "-numeric", Switch: true, Help: "Compare elements as numbers; it is an error for one not to be a number."},
+		&Argument{Name: "-dictionary", Switch: true, Help: "Compare elements as strings, ignoring case except to break ties and comparing runs of digits as integers, so a2 sorts before a10."},
+		&Argument{Name: "-nocase", Switch: true, Help: "Ignore case when comparing strings."},
+		&Argument{Name: "-unique", Switch: true, Help: "Keep only the first of each run of equal elements."},
+		&Argument{
+			Name:    "-index",
+			Help:    "Compare elements by their element at this index, or list of nested indices, instead of as a whole.",
+			Default: EmptyToken,
+		},
+		&Argument{
+			Name:    "-command",
+			Help:    "Compare elements by calling this command with two elements appended; it must return a negative integer, zero or a positive integer as the first is less than, equal to or greater than the second.",
+			Default: EmptyToken,
+		},
+		&Argument{
+			Name:    "-stride",
+			Help:    "Sort {list} as groups of this many elements, compared by the first element of each group or as given by -index.",
+			Default: NewToken(1),
+			Coerce:  Proc(ProcInt).AsToken("int"),
+		},
+		ArgHelp("list", "The list to sort."),
+	)
+	as.Help = "Returns a sorted copy of {list}. The sort is stable: equal elements keep their order. " +
+		"Unless one of -ascii, -numeric or -dictionary is given, elements are compared as with [eq]."
 
-	list, err := args[1].AsList()
+	parsedArgs, err := as.BindArgs(interp, args)
 	if err != nil {
+		as.ShowUsage(interp.Stderr)
 		return EmptyToken, err
 	}
 
-	sort.Sort(List(list))
+	var coll Collation
+	modes := 0
+	for mode, name := range map[CompareMode]string{
+		CompareASCII:      "ascii",
+		CompareNumeric:    "numeric",
+		CompareDictionary: "dictionary",
+	} {
+		if parsedArgs[name].Data.(bool) {
+			coll.Mode = mode
+			modes++
+		}
+	}
+	if modes > 1 {
+		return EmptyToken, ErrCommand(args[0].String, "only one of -ascii, -numeric and -dictionary may be given")
+	}
+	coll.NoCase = parsedArgs["nocase"].Data.(bool)
+
+	list, err := parsedArgs["list"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["list"].String)
+	}
+
+	stride, _ := parsedArgs["stride"].AsInt()
+	if stride < 1 {
+		return EmptyToken, ErrCommand(args[0].String, "stride must be at least 1")
+	}
+	if len(list)%stride != 0 {
+		return EmptyToken, ErrCommand(args[0].String, "list length must be a multiple of the stride")
+	}
+
+	idxList, err := parsedArgs["index"].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(parsedArgs["index"].String)
+	}
+	indices := make([]int, len(idxList))
+	for i, idxTok := range idxList {
+		if indices[i], err = strictIntFromToken(idxTok); err != nil {
+			return EmptyToken, err
+		}
+	}
+	if stride > 1 && len(indices) == 0 {
+		indices = []int{0}
+	}
+
+	// group list into records of stride elements, each with the key it's
+	// sorted by
+	type record struct {
+		key  *Token
+		elem []*Token
+	}
+	records := make([]record, 0, len(list)/stride)
+	for i := 0; i < len(list); i += stride {
+		rec := record{key: list[i], elem: list[i : i+stride]}
+		if stride > 1 {
+			rec.key = NewList(rec.elem)
+		}
+		if rec.key, err = sortKey(rec.key, indices); err != nil {
+			return EmptyToken, ErrCommand(args[0].String, err.Error())
+		}
+		records = append(records, rec)
+	}
+
+	compare := func(a, b *Token) int {
+		return coll.Compare(a, b)
+	}
+	var cmdErr error
+	if parsedArgs["command"].String != "" {
+		cmdPrefix, err := parsedArgs["command"].AsCommand()
+		if err != nil {
+			return EmptyToken, err
+		}
+		compare = func(a, b *Token) int {
+			if cmdErr != nil {
+				return 0
+			}
+			// use ExecLiteral so the elements don't get re-interpretted
+			ret, err := interp.ExecLiteral(slices.Concat(cmdPrefix, Command{a, b}))
+			if err != nil {
+				cmdErr = err
+				return 0
+			}
+			r, err := strictIntFromToken(ret)
+			if err != nil {
+				cmdErr = ErrCommand(args[0].String, fmt.Sprintf("comparison command returned %q, expected an integer", ret.String))
+			}
+			return r
+		}
+	} else {
+		for _, rec := range records {
+			if err := coll.Check(rec.key); err != nil {
+				return EmptyToken, err
+			}
+		}
+	}
+
+	decreasing := parsedArgs["decreasing"].Data.(bool)
+	slices.SortStableFunc(records, func(a, b record) int {
+		if decreasing {
+			return compare(b.key, a.key)
+		}
+		return compare(a.key, b.key)
+	})
+	if cmdErr != nil {
+		return EmptyToken, cmdErr
+	}
+
+	if parsedArgs["unique"].Data.(bool) {
+		records = slices.CompactFunc(records, func(a, b record) bool {
+			return compare(a.key, b.key) == 0
+		})
+		if cmdErr != nil {
+			return EmptyToken, cmdErr
+		}
+	}
 
-	return NewList(list), nil
+	sorted := make([]*Token, 0, len(list))
+	for _, rec := range records {
+		sorted = append(sorted, rec.elem...)
+	}
+
+	return NewList(sorted), nil
+}
+
+// sortKey returns the element of tok at the nested indices, counting from
+// the end for negative ones.
+func sortKey(tok *Token, indices []int) (*Token, error) {
+	for _, idx := range indices {
+		list, err := tok.AsList()
+		if err != nil {
+			return EmptyToken, ErrExpectedList(tok.String)
+		}
+		if idx < -len(list) || idx >= len(list) {
+			return EmptyToken, fmt.Errorf("element %s has no index %d", tok.Quoted(), idx)
+		}
+		tok = tok.Index(idx)
+	}
+	return tok, nil
 }
 
 func ProcListReverse(interp *Interp, args []*Token) (*Token, error) {
@@ -260,76 +419,47 @@ func ProcListMap(interp *Interp, args []*Token) (*Token, error) {
 	// any error aborts
 	// break stops processing but doesn't throw an error
 	// list::map <list> <proc>
-	as := NewArgSet("list::map",
-		&Argument{
-			Name:    "-skiperrors",
-			Help:    "If true, any errors encountered while calling {proc} are simply skipped (as though continue were called) rather than stopping with an error.",
-			Default: FalseToken,
-			Coerce:  Proc(ProcBool).AsToken("bool"),
-		},
-		ArgHelp("list", "The list to iterate over."),
-		ArgHelp("proc", "The proc to call for each list element."),
-	)
-	as.Help = "Iterates over {list} calling {proc} with each element from {list} appended to it. A new list is generated from the return values from calling {proc}. The returned list has the same number of elements as {list}. If {proc} returns by calling [continue], that element is skipped; i.e. the returned list is one element shorter than {list} for each time that [continue] is used. If {proc} returns using [break], the list is truncated at that element."
-
-	parsedArgs, err := as.BindArgs(interp, args)
+	as := listProcArgSet("list::map", "Iterates over {list} calling {proc} with each element from {list} appended to it. A new list is generated from the return values from calling {proc}. The returned list has the same number of elements as {list}. If {proc} returns by calling [continue], that element is skipped; i.e. the returned list is one element shorter than {list} for each time that [continue] is used. If {proc} returns using [break], the list is truncated at that element.")
+	list, cmd, skipErrors, err := bindListProc(interp, as, args)
 	if err != nil {
-		as.ShowUsage(interp.Stderr)
 		return EmptyToken, err
 	}
 
-	list, err := parsedArgs["list"].AsList()
+	outList := make([]*Token, 0, len(list))
+	err = listIterate(interp, list, cmd, skipErrors, func(_, ret *Token) error {
+		outList = append(outList, ret)
+		return nil
+	})
 	if err != nil {
 		return EmptyToken, err
 	}
 
-	outList := make([]*Token, 0, len(list))
-	cmdPrefix, _ := parsedArgs["proc"].AsCommand()
-
-	for _, e := range list {
-		cmd := append(cmdPrefix, e)
-		// use ExecLiteral so the elements of the list don't get re-interpretted
-		ret, err := interp.ExecLiteral(cmd)
-		switch err {
-		case nil:
-			outList = append(outList, ret)
-		case ErrContinue:
-			// skip this element
-		case ErrBreak:
-			// truncate result and return without error
-			return NewList(outList), nil
-		default:
-			if parsedArgs["skiperrors"].Data.(bool) {
-				continue
-			}
-			return EmptyToken, err
-		}
-	}
-
 	return NewList(outList), nil
 }
 
 func ProcListUniq(interp *Interp, args []*Token) (*Token, error) {
+	coll, args := parseCompareFlags(args)
 	as := NewArgSet(args[0].String,
 		&Argument{
 			Name: "list",
 			Help: "A list.",
 		})
-	as.Help = "Returns a list that has replaced consecutive runs of equal elements with a single copy."
+	as.Help = "Returns a list that has replaced consecutive runs of equal elements with a single copy. " +
+		"Options -nocase, -ascii, -numeric and -dictionary, given before {list}, select how elements are compared."
 	parsedArgs, err := as.BindArgs(interp, args)
 	if err != nil {
 		as.ShowUsage(interp.Stderr)
 		return EmptyToken, err
 	}
-	// shallow uniq? just .String and not .Data? means true and on won't match...
 	list, err := parsedArgs["list"].AsList()
 	if err != nil {
 		return EmptyToken, err
 	}
+	if err := coll.Check(list...); err != nil {
+		return EmptyToken, err
+	}
 
-	compactList := slices.CompactFunc(list, func(a *Token, b *Token) bool {
-		return a.String == b.String
-	})
+	compactList := slices.CompactFunc(slices.Clone(list), coll.Equal)
 
 	return NewList(compactList), nil
 }
@@ -359,13 +489,20 @@ func ProcListAppend(interp *Interp, args []*Token) (*Token, error) {
 	return interp.SetVar(args[1].String, NewList(newList))
 }
 
+// list::contains ?options? list value reports whether list has an element
+// equal to value. Options -nocase, -ascii, -numeric and -dictionary select
+// the comparison; see Collation.
 func ProcListContains(interp *Interp, args []*Token) (*Token, error) {
+	coll, args := parseCompareFlags(args)
+	if len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
 	list, err := args[1].AsList()
 	if err != nil {
 		return EmptyToken, err
 	}
 	for e := range list {
-		if list[e].Equal(args[2]) {
+		if coll.Equal(list[e], args[2]) {
 			return TrueToken, nil
 		}
 	}
@@ -432,59 +569,29 @@ func ProcListFind(interp *Interp, args []*Token) (*Token, error) {
 	list, _ := parsedArgs["list"].AsList()
 	out := make([]*Token, 0, len(list))
 
-	pattern := parsedArgs["pattern"].String
-	switch parsedArgs["type"].String {
-	case "exact":
-		if parsedArgs["matchcase"].Data.(bool) {
-			for i := range list {
-				if list[i].Equal(parsedArgs["pattern"]) {
-					out = append(out, list[i])
-				}
-			}
-		} else {
-			for i := range list {
-				if strings.ToLower(list[i].String) == strings.ToLower(pattern) {
-					out = append(out, list[i])
-				}
-			}
+	if parsedArgs["type"].String == "exact" {
+		coll := Collation{NoCase: !parsedArgs["matchcase"].Data.(bool)}
+		if coll.NoCase {
+			// a case-insensitive match is always by string
+			coll.Mode = CompareASCII
 		}
-	case "glob":
-		if parsedArgs["matchcase"].Data.(bool) {
-			for i := range list {
-				if match, _ := filepath.Match(
-					parsedArgs["pattern"].String,
-					list[i].String); match {
-					out = append(out, list[i])
-				}
-			}
-		} else {
-			pattern = strings.ToLower(pattern)
-			for i := range list {
-				if match, _ := filepath.Match(
-					pattern,
-					strings.ToLower(list[i].String)); match {
-					out = append(out, list[i])
-				}
+		for i := range list {
+			if coll.Equal(list[i], parsedArgs["pattern"]) {
+				out = append(out, list[i])
 			}
 		}
-	case "regex":
-		return EmptyToken, ErrNotImplemented
+		return NewList(out), nil
 	}
 
-	return NewList(out), nil
-}
-
-/*
-func ProcListJoin(interp *Interp, args []*Token) (*Token, error) {
-	as := NewArgSet(
-		&Argument{
-			Name: "list",
-		},
-		&Argument{
-			Name: "joinString",
-		},
-	)
+	match, err := stringMatcher(parsedArgs["type"].String, parsedArgs["pattern"], parsedArgs["matchcase"].Data.(bool))
+	if err != nil {
+		return EmptyToken, err
+	}
+	for i := range list {
+		if match(list[i].String) {
+			out = append(out, list[i])
+		}
+	}
 
-	// parsedArgs, err := as.Parse(args[1:])
+	return NewList(out), nil
 }
-*/
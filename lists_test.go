@@ -0,0 +1,64 @@
+package adz
+
+import (
+	"testing"
+)
+
+func TestListSort(t *testing.T) {
+	interp := NewInterp()
+	interp.ExecString(`proc bylen {a b} { - [list::len $a] [list::len $b] }`)
+
+	runScripts(t, interp, []scriptTest{
+		{`list::sort {b c a}`, "a b c"},
+		{`list::sort {10 9 100}`, "9 10 100"},
+		{`list::sort -ascii {10 9 100}`, "10 100 9"},
+		{`list::sort -numeric {10 9 1e1 0x0a}`, "9 10 1e1 0x0a"},
+		{`list::sort -decreasing {10 9 1e1 0x0a}`, "10 1e1 0x0a 9"},
+		{`list::sort -dictionary {a10 A2 a2 a1}`, "a1 A2 a2 a10"},
+		{`list::sort -nocase {b A a B}`, "A a b B"},
+		{`list::sort -unique {c a b a c}`, "a b c"},
+		{`list::sort -decr -uniq {1 3 2 3.0}`, "3 2 1"},
+		{`list::sort -index 1 {{a 3} {b 1} {c 2}}`, "{b 1} {c 2} {a 3}"},
+		{`list::sort -index {1 0} {{a {3 x}} {b {1 y}}}`, "{b {1 y}} {a {3 x}}"},
+		{`list::sort -index -1 {{a 3} {b 1 0}}`, "{b 1 0} {a 3}"},
+		{`list::sort -stride 2 {b 1 a 2 c 3}`, "a 2 b 1 c 3"},
+		{`list::sort -stride 2 -index 1 -decreasing {b 1 a 2 c 3}`, "c 3 a 2 b 1"},
+		{`list::sort -command bylen {{c c c} a {b b} {d d}}`, "a {b b} {d d} {c c c}"},
+		{`list::sort -command bylen -decreasing {{c c c} a {b b} {d d}}`, "{c c c} {b b} {d d} a"},
+		{`list::sort -command bylen -unique {{c c c} a {b b} {d d}}`, "a {b b} {c c c}"},
+		{`list::sort -- {-1 -3 2}`, "-3 -1 2"},
+		{`list::sort {}`, ""},
+	})
+}
+
+func TestListSort_Stable(t *testing.T) {
+	interp := NewInterp()
+	out, err := interp.ExecString(`list::sort -index 0 {{1 a} {0 b} {1 c} {0 d} {1 e}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "{0 b} {0 d} {1 a} {1 c} {1 e}"; out.String != want {
+		t.Errorf("expected %q, got %q", want, out.String)
+	}
+	out, err = interp.ExecString(`list::sort -index 0 -decreasing {{1 a} {0 b} {1 c} {0 d} {1 e}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "{1 a} {1 c} {1 e} {0 b} {0 d}"; out.String != want {
+		t.Errorf("expected %q, got %q", want, out.String)
+	}
+}
+
+func TestListSort_Errors(t *testing.T) {
+	interp := NewInterp()
+	interp.ExecString(`proc bad {a b} { return x }`)
+	runScriptErrors(t, interp, []scriptTest{
+		{`list::sort -numeric {1 x 2}`, "expected number"},
+		{`list::sort -numeric -ascii {1 2}`, "only one of"},
+		{`list::sort -stride 2 {a b c}`, "multiple of the stride"},
+		{`list::sort -stride 0 {a b}`, "at least 1"},
+		{`list::sort -index 2 {{a b} {c d}}`, "no index 2"},
+		{`list::sort -command bad {a b}`, "expected an integer"},
+		{`list::sort -command nosuchproc {a b}`, "nosuchproc"},
+	})
+}
@@ -0,0 +1,475 @@
+package adz
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+var (
+	tokenMarshalerType = reflect.TypeOf((*TokenMarshaler)(nil)).Elem()
+	tokenPtrType       = reflect.TypeOf((*Token)(nil))
+)
+
+// Marshal returns the token form of v, the converse of Unmarshal.
+//
+// Values implementing TokenMarshaler or encoding.TextMarshaler (such as
+// time.Time) marshal themselves. Otherwise bools, numbers and strings become
+// their usual string forms, slices and arrays become lists, and maps and
+// structs become key/value lists; map keys are sorted. Nil pointers and
+// interfaces become the empty token, and the fields of a nil embedded struct
+// pointer are left out. A value that contains itself is an error.
+//
+// Struct fields are named by their adz tag, if any, as with encoding/json:
+//
+//	Port int `adz:"port"`           ;# key is port
+//	Host string `adz:",omitempty"` ;# left out when empty
+//	Secret string `adz:"-"`        ;# never marshaled
+//
+// Unexported fields are skipped and embedded structs without a tag have their
+// fields promoted.
+func Marshal(v any) (*Token, error) {
+	ms := &marshalState{seen: map[seenKey]struct{}{}}
+	return ms.marshal(reflect.ValueOf(v))
+}
+
+// Unmarshal parses tok into the value v points to, allocating maps, slices
+// and pointers as needed. It follows the same rules as Marshal, also
+// honoring TokenUnmarshaler and encoding.TextUnmarshaler. Keys of a
+// key/value list that don't match any struct field are ignored. The empty
+// token unmarshals into a pointer as nil.
+func Unmarshal(tok *Token, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("adz: Unmarshal expects a non-nil pointer, got %T", v)
+	}
+	return unmarshalValue(tok, rv.Elem())
+}
+
+// marshalState tracks the pointers, maps and slices Marshal is inside of,
+// so that a value containing itself is an error rather than recursing
+// forever.
+type marshalState struct {
+	seen map[seenKey]struct{}
+}
+
+type seenKey struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+	len int
+}
+
+// enter records rv, which must be a non-nil pointer, map or slice, as being
+// marshaled, failing if it already is. The returned func undoes it.
+func (ms *marshalState) enter(rv reflect.Value) (func(), error) {
+	key := seenKey{ptr: rv.UnsafePointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		key.len = rv.Len()
+	}
+	if _, ok := ms.seen[key]; ok {
+		return nil, fmt.Errorf("adz: cannot marshal cyclic value of type %s", rv.Type())
+	}
+	ms.seen[key] = struct{}{}
+	return func() { delete(ms.seen, key) }, nil
+}
+
+func (ms *marshalState) marshal(rv reflect.Value) (*Token, error) {
+	if !rv.IsValid() {
+		return EmptyToken, nil
+	}
+
+	t := rv.Type()
+	switch {
+	case t == tokenPtrType:
+		if rv.IsNil() {
+			return EmptyToken, nil
+		}
+		return rv.Interface().(*Token), nil
+	case t == tokenPtrType.Elem():
+		tok := rv.Interface().(Token)
+		return &tok, nil
+	case t.Implements(tokenMarshalerType):
+		if isNilable(rv) && rv.IsNil() {
+			return EmptyToken, nil
+		}
+		return rv.Interface().(TokenMarshaler).MarshalToken()
+	case rv.CanAddr() && reflect.PointerTo(t).Implements(tokenMarshalerType):
+		return rv.Addr().Interface().(TokenMarshaler).MarshalToken()
+	case t.Implements(textMarshalerType):
+		if isNilable(rv) && rv.IsNil() {
+			return EmptyToken, nil
+		}
+		buf, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		return NewTokenBytes(buf), err
+	case rv.CanAddr() && reflect.PointerTo(t).Implements(textMarshalerType):
+		buf, err := rv.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return NewTokenBytes(buf), err
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			break
+		}
+		leave, err := ms.enter(rv)
+		if err != nil {
+			return EmptyToken, err
+		}
+		defer leave()
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return EmptyToken, nil
+		}
+		return ms.marshal(rv.Elem())
+	case reflect.Bool:
+		return NewTokenString(strconv.FormatBool(rv.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewTokenString(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewTokenString(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return NewTokenString(strconv.FormatFloat(rv.Float(), 'g', -1, t.Bits())), nil
+	case reflect.String:
+		return NewTokenString(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return NewTokenBytes(rv.Bytes()), nil
+		}
+		list := make([]*Token, rv.Len())
+		for i := range list {
+			elem, err := ms.marshal(rv.Index(i))
+			if err != nil {
+				return EmptyToken, fmt.Errorf("element %d: %w", i, err)
+			}
+			list[i] = elem
+		}
+		return NewList(list), nil
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		vals := make(map[string]reflect.Value, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			key, err := ms.marshal(iter.Key())
+			if err != nil {
+				return EmptyToken, fmt.Errorf("map key: %w", err)
+			}
+			keys = append(keys, key.String)
+			vals[key.String] = iter.Value()
+		}
+		sort.Strings(keys)
+		list := make([]*Token, 0, 2*len(keys))
+		for _, key := range keys {
+			val, err := ms.marshal(vals[key])
+			if err != nil {
+				return EmptyToken, fmt.Errorf("key %s: %w", key, err)
+			}
+			list = append(list, NewTokenString(key), val)
+		}
+		return NewList(list), nil
+	case reflect.Struct:
+		var list []*Token
+		for _, cf := range codecFieldsOf(t) {
+			fv, err := rv.FieldByIndexErr(cf.index)
+			if err != nil {
+				// inside a nil embedded struct pointer
+				continue
+			}
+			if cf.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			val, err := ms.marshal(fv)
+			if err != nil {
+				return EmptyToken, fmt.Errorf("field %s: %w", cf.name, err)
+			}
+			list = append(list, NewTokenString(cf.name), val)
+		}
+		return NewList(list), nil
+	}
+
+	return EmptyToken, fmt.Errorf("adz: cannot marshal %s", t)
+}
+
+func unmarshalValue(tok *Token, rv reflect.Value) error {
+	t := rv.Type()
+
+	if t == tokenPtrType {
+		rv.Set(reflect.ValueOf(tok))
+		return nil
+	}
+	if rv.Kind() == reflect.Pointer {
+		if tok.String == "" && tok.Data == nil {
+			rv.SetZero()
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(t.Elem()))
+		}
+		return unmarshalValue(tok, rv.Elem())
+	}
+
+	switch ptr := rv.Addr().Interface().(type) {
+	case TokenUnmarshaler:
+		return ptr.UnmarshalToken(tok)
+	case encoding.TextUnmarshaler:
+		return ptr.UnmarshalText([]byte(tok.String))
+	}
+
+	// the token may already be holding what we want, e.g. a GoObject
+	if data := tok.Data; data != nil {
+		if ier, ok := data.(Interfacer); ok {
+			data = ier.Interface()
+		}
+		if dv := reflect.ValueOf(data); dv.Type().AssignableTo(t) {
+			rv.Set(dv)
+			return nil
+		} else if dv.Kind() == reflect.Pointer && !dv.IsNil() && dv.Type().Elem().AssignableTo(t) {
+			rv.Set(dv.Elem())
+			return nil
+		}
+	}
+
+	s := strings.TrimSpace(tok.String)
+	switch rv.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		rv.Set(reflect.ValueOf(tok.String))
+		return nil
+	case reflect.Bool:
+		b, err := tok.AsBool()
+		if err != nil {
+			return ErrExpectedBool(tok.String)
+		}
+		rv.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, t.Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s, got %q", t, tok.String)
+		}
+		rv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 0, t.Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s, got %q", t, tok.String)
+		}
+		rv.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s, got %q", t, tok.String)
+		}
+		rv.SetFloat(f)
+		return nil
+	case reflect.String:
+		rv.SetString(tok.String)
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes([]byte(tok.String))
+			return nil
+		}
+		list, err := tok.AsList()
+		if err != nil {
+			return ErrExpectedList(tok.String)
+		}
+		slice := reflect.MakeSlice(t, len(list), len(list))
+		for i := range list {
+			if err := unmarshalValue(list[i], slice.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		rv.Set(slice)
+		return nil
+	case reflect.Array:
+		list, err := tok.AsList()
+		if err != nil {
+			return ErrExpectedList(tok.String)
+		}
+		if len(list) > rv.Len() {
+			return fmt.Errorf("expected at most %d elements, got %d", rv.Len(), len(list))
+		}
+		rv.SetZero()
+		for i := range list {
+			if err := unmarshalValue(list[i], rv.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		list, err := keyValues(tok)
+		if err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(t, len(list)/2))
+		}
+		for i := 0; i < len(list); i += 2 {
+			key := reflect.New(t.Key()).Elem()
+			if err := unmarshalValue(list[i], key); err != nil {
+				return fmt.Errorf("map key %s: %w", list[i].String, err)
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := unmarshalValue(list[i+1], val); err != nil {
+				return fmt.Errorf("key %s: %w", list[i].String, err)
+			}
+			rv.SetMapIndex(key, val)
+		}
+		return nil
+	case reflect.Struct:
+		list, err := keyValues(tok)
+		if err != nil {
+			return err
+		}
+		fields := codecFieldsOf(t)
+		for i := 0; i < len(list); i += 2 {
+			cf := fields.lookup(list[i].String)
+			if cf == nil {
+				continue
+			}
+			fv, err := fieldByIndexAlloc(rv, cf.index)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", cf.name, err)
+			}
+			if err := unmarshalValue(list[i+1], fv); err != nil {
+				return fmt.Errorf("field %s: %w", cf.name, err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("adz: cannot unmarshal into %s", t)
+}
+
+// keyValues returns tok as a list with an even number of elements.
+func keyValues(tok *Token) ([]*Token, error) {
+	list, err := tok.AsList()
+	if err != nil {
+		return nil, ErrExpectedList(tok.String)
+	}
+	if len(list)%2 != 0 {
+		return nil, fmt.Errorf("expected key/value list, got %d elements", len(list))
+	}
+	return list, nil
+}
+
+// fieldByIndexAlloc is like FieldByIndex, but allocates nil embedded struct
+// pointers along the way. As with encoding/json, a nil pointer to an
+// unexported struct type can't be set, and is an error.
+func fieldByIndexAlloc(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, fmt.Errorf("adz: cannot set embedded pointer to unexported struct %s", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+func isNilable(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// codecField is a struct field as Marshal and Unmarshal see it.
+type codecField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type codecFields []*codecField
+
+func (cfs codecFields) lookup(name string) *codecField {
+	for _, cf := range cfs {
+		if cf.name == name {
+			return cf
+		}
+	}
+	// like encoding/json, fall back to a case-insensitive match
+	for _, cf := range cfs {
+		if strings.EqualFold(cf.name, name) {
+			return cf
+		}
+	}
+	return nil
+}
+
+var codecFieldsCache sync.Map // reflect.Type -> codecFields
+
+// codecFieldsOf returns the marshaled fields of the struct type t, in order,
+// computing them on first use.
+func codecFieldsOf(t reflect.Type) codecFields {
+	if cfs, ok := codecFieldsCache.Load(t); ok {
+		return cfs.(codecFields)
+	}
+
+	var cfs codecFields
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag, hasTag := sf.Tag.Lookup("adz")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+
+			idx := append(append([]int{}, index...), i)
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, idx)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			cfs = append(cfs, &codecField{
+				name:      name,
+				index:     idx,
+				omitEmpty: hasTag && strings.Contains(","+opts+",", ",omitempty,"),
+			})
+		}
+	}
+	walk(t, nil)
+
+	actual, _ := codecFieldsCache.LoadOrStore(t, cfs)
+	return actual.(codecFields)
+}
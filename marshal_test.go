@@ -0,0 +1,232 @@
+package adz
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type marshalInner struct {
+	Port  int    `adz:"port"`
+	Proto string `adz:"proto,omitempty"`
+}
+
+type marshalBase struct {
+	ID int `adz:"id"`
+}
+
+type marshalOuter struct {
+	marshalBase
+	Name    string            `adz:"name"`
+	Tags    []string          `adz:"tags"`
+	Labels  map[string]int    `adz:"labels,omitempty"`
+	Inner   marshalInner      `adz:"inner"`
+	Backup  *marshalInner     `adz:"backup,omitempty"`
+	Enabled bool              `adz:"enabled"`
+	Ratio   float64           `adz:"ratio"`
+	When    time.Time         `adz:"when"`
+	Grid    [2][2]int         `adz:"grid"`
+	Secret  string            `adz:"-"`
+	Custom  marshalCustom     `adz:"custom"`
+	Extra   map[string]string `adz:",omitempty"`
+	hidden  int
+}
+
+// marshalCustom is a TokenMarshaler/TokenUnmarshaler storing its value
+// upper-cased.
+type marshalCustom struct{ s string }
+
+func (c marshalCustom) MarshalToken() (*Token, error) {
+	return NewTokenString(strings.ToUpper(c.s)), nil
+}
+
+func (c *marshalCustom) UnmarshalToken(tok *Token) error {
+	c.s = strings.ToLower(tok.String)
+	return nil
+}
+
+func TestMarshal(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	v := marshalOuter{
+		marshalBase: marshalBase{ID: 7},
+		Name:        "web server",
+		Tags:        []string{"a", "b c"},
+		Inner:       marshalInner{Port: 80},
+		Enabled:     true,
+		Ratio:       0.25,
+		When:        when,
+		Grid:        [2][2]int{{1, 2}, {3, 4}},
+		Secret:      "shh",
+		Custom:      marshalCustom{"hi"},
+		hidden:      1,
+	}
+
+	tok, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `id 7 name {web server} tags {a {b c}} inner {port 80} enabled true ratio 0.25 when 2024-05-06T07:08:09Z grid {{1 2} {3 4}} custom HI`
+	if tok.String != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, tok.String)
+	}
+
+	var got marshalOuter
+	if err := Unmarshal(NewTokenString(tok.String), &got); err != nil {
+		t.Fatal(err)
+	}
+	v.Secret, v.hidden = "", 0
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip mismatch:\nwant %+v\ngot  %+v", v, got)
+	}
+}
+
+func TestMarshal_Collections(t *testing.T) {
+	for _, tc := range []struct {
+		v    any
+		want string
+	}{
+		{[]int{1, 2, 3}, "1 2 3"},
+		{[]string{}, ""},
+		{map[string]int{"b": 2, "a": 1}, "a 1 b 2"},
+		{map[int]bool{2: false, 1: true}, "1 true 2 false"},
+		{[]*marshalInner{{Port: 1, Proto: "tcp"}, nil}, "{port 1 proto tcp} {}"},
+		{[]byte("raw bytes"), "raw bytes"},
+		{uint8(255), "255"},
+		{nil, ""},
+	} {
+		tok, err := Marshal(tc.v)
+		if err != nil {
+			t.Fatalf("%#v: %v", tc.v, err)
+		}
+		if tok.String != tc.want {
+			t.Errorf("%#v: expected %q, got %q", tc.v, tc.want, tok.String)
+		}
+	}
+
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Errorf("expected error marshaling a chan")
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var m map[string][]int
+	if err := Unmarshal(NewTokenString(`x {1 2} y {0x10}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string][]int{"x": {1, 2}, "y": {16}}) {
+		t.Errorf("unexpected map %v", m)
+	}
+
+	// case-insensitive field names; unknown keys are ignored
+	var in marshalInner
+	if err := Unmarshal(NewTokenString(`PORT 8080 bogus 1`), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Port != 8080 {
+		t.Errorf("expected port 8080, got %d", in.Port)
+	}
+
+	// the token's Data is used as is when it fits
+	var p *marshalInner
+	obj := WrapObject(&marshalInner{Port: 1}, nil)
+	if err := Unmarshal(obj, &p); err != nil || p == nil || p.Port != 1 {
+		t.Errorf("expected pointer from GoObject, got %v, %v", p, err)
+	}
+
+	for _, tc := range []struct {
+		tok string
+		v   any
+	}{
+		{`1 2 3`, &map[string]int{}},
+		{`abc`, new(int)},
+		{`300`, new(uint8)},
+		{`maybe`, new(bool)},
+		{`1 2 3`, new([2]int)},
+		{`port x`, new(marshalInner)},
+		{`not-a-time`, new(time.Time)},
+	} {
+		if err := Unmarshal(NewTokenString(tc.tok), tc.v); err == nil {
+			t.Errorf("%s into %T: expected error", tc.tok, tc.v)
+		}
+	}
+
+	if err := Unmarshal(NewTokenString("1"), in); err == nil {
+		t.Errorf("expected error unmarshaling into a non-pointer")
+	}
+}
+
+// marshalEmbedPtr embeds a pointer to an unexported struct type, whose
+// exported fields are promoted.
+type marshalEmbedPtr struct {
+	*marshalUnexported
+	Y int
+}
+
+type marshalUnexported struct {
+	X int
+}
+
+func TestUnmarshal_EmbeddedPointer(t *testing.T) {
+	// nil, so there's nowhere to put X
+	var v marshalEmbedPtr
+	err := Unmarshal(NewTokenString("X 1 Y 2"), &v)
+	if err == nil || !strings.Contains(err.Error(), "cannot set embedded pointer to unexported struct") {
+		t.Errorf("expected embedded pointer error, got %v", err)
+	}
+
+	v = marshalEmbedPtr{marshalUnexported: &marshalUnexported{}}
+	if err := Unmarshal(NewTokenString("X 1 Y 2"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.X != 1 || v.Y != 2 {
+		t.Errorf("expected X 1 Y 2, got %+v", v)
+	}
+}
+
+func TestMarshal_EmbeddedPointer(t *testing.T) {
+	tok, err := Marshal(marshalEmbedPtr{Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.String != "Y 2" {
+		t.Errorf("expected nil embedded pointer's fields left out, got %q", tok.String)
+	}
+
+	tok, err = Marshal(marshalEmbedPtr{marshalUnexported: &marshalUnexported{X: 1}, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.String != "X 1 Y 2" {
+		t.Errorf("expected X 1 Y 2, got %q", tok.String)
+	}
+}
+
+type marshalNode struct {
+	Name string
+	Next *marshalNode
+}
+
+func TestMarshal_Cycle(t *testing.T) {
+	a := &marshalNode{Name: "a"}
+	a.Next = &marshalNode{Name: "b", Next: a}
+	if _, err := Marshal(a); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	if _, err := Marshal(m); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+
+	// the same pointer twice isn't a cycle
+	b := &marshalNode{Name: "b"}
+	tok, err := Marshal([]*marshalNode{b, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.String != "{Name b Next {}} {Name b Next {}}" {
+		t.Errorf("unexpected %q", tok.String)
+	}
+}
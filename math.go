@@ -1,9 +1,10 @@
 package adz
 
 import (
+	"cmp"
 	"fmt"
-	"strconv"
-	"strings"
+	"math"
+	"math/big"
 )
 
 func init() {
@@ -33,90 +34,130 @@ func init() {
 	StdLib["<<"] = ProcLeftShift
 	StdLib["rshift"] = ProcRightShift
 	StdLib[">>"] = ProcRightShift
-	StdLib["lt"] = procNumericCmp(lessThan)
-	StdLib["<"] = procNumericCmp(lessThan)
-	StdLib["lte"] = procNumericCmp(lessThanOrEqual)
-	StdLib["<="] = procNumericCmp(lessThanOrEqual)
-	StdLib["gt"] = procNumericCmp(greaterThan)
-	StdLib[">"] = procNumericCmp(greaterThan)
-	StdLib["gte"] = procNumericCmp(greaterThanOrEqual)
-	StdLib[">="] = procNumericCmp(greaterThanOrEqual)
+	StdLib["lt"] = procCompare(func(c int) bool { return c < 0 })
+	StdLib["<"] = StdLib["lt"]
+	StdLib["lte"] = procCompare(func(c int) bool { return c <= 0 })
+	StdLib["<="] = StdLib["lte"]
+	StdLib["gt"] = procCompare(func(c int) bool { return c > 0 })
+	StdLib[">"] = StdLib["gt"]
+	StdLib["gte"] = procCompare(func(c int) bool { return c >= 0 })
+	StdLib[">="] = StdLib["gte"]
 }
 
 type numericValue struct {
 	i       int
 	f       float64
+	big     *big.Int // set instead of i for integers too big for an int
 	isFloat bool
 }
 
 func numericFromToken(tok *Token) (numericValue, error) {
 	switch v := tok.Data.(type) {
 	case int:
-		return numericValue{i: v, f: float64(v)}, nil
+		return intValue(v), nil
 	case Integer:
-		i := v.Int()
-		return numericValue{i: i, f: float64(i)}, nil
+		return intValue(v.Int()), nil
+	case *big.Int:
+		return bigValue(v), nil
 	case float64:
-		return numericValue{f: v, isFloat: true}, nil
+		return floatValue(v), nil
 	case Floater:
-		return numericValue{f: v.Float(), isFloat: true}, nil
+		return floatValue(v.Float()), nil
 	}
 
-	if strings.ContainsAny(tok.String, ".eE") {
-		f, err := tok.AsFloat()
-		if err != nil {
-			return numericValue{}, fmt.Errorf("expected number, got %q", tok.String)
-		}
-		return numericValue{f: f, isFloat: true}, nil
+	n, ok := parseNumber(tok.String)
+	if !ok {
+		return numericValue{}, ErrExpectedNumber(tok.String)
 	}
-
-	if i, err := tok.AsInt(); err == nil {
-		return numericValue{i: i, f: float64(i)}, nil
+	if tok.Data == nil {
+		tok.Data = n.data()
 	}
-	f, err := tok.AsFloat()
-	if err != nil {
-		return numericValue{}, fmt.Errorf("expected number, got %q", tok.String)
+	return n, nil
+}
+
+// data returns n as the Go value its token holds in Data.
+func (n numericValue) data() any {
+	switch {
+	case n.isFloat:
+		return n.f
+	case n.big != nil:
+		return n.big
 	}
-	return numericValue{f: f, isFloat: true}, nil
+	return n.i
 }
 
 func (n numericValue) Float64() float64 {
-	if n.isFloat {
+	switch {
+	case n.isFloat:
 		return n.f
+	case n.big != nil:
+		f, _ := new(big.Float).SetInt(n.big).Float64()
+		return f
 	}
 	return float64(n.i)
 }
 
 func (n numericValue) Token() *Token {
-	if n.isFloat {
+	switch {
+	case n.isFloat:
 		return NewToken(n.f)
+	case n.big != nil:
+		return &Token{String: n.big.String(), Data: n.big}
 	}
 	return NewTokenInt(n.i)
 }
 
 func (n numericValue) Add(other numericValue) numericValue {
 	if n.isFloat || other.isFloat {
-		return numericValue{f: n.Float64() + other.Float64(), isFloat: true}
+		return floatValue(n.Float64() + other.Float64())
 	}
-	return numericValue{i: n.i + other.i, f: float64(n.i + other.i)}
+	return integerOp(n, other, addInt, (*big.Int).Add)
 }
 
 func (n numericValue) Sub(other numericValue) numericValue {
 	if n.isFloat || other.isFloat {
-		return numericValue{f: n.Float64() - other.Float64(), isFloat: true}
+		return floatValue(n.Float64() - other.Float64())
 	}
-	return numericValue{i: n.i - other.i, f: float64(n.i - other.i)}
+	return integerOp(n, other, subInt, (*big.Int).Sub)
 }
 
 func (n numericValue) Mul(other numericValue) numericValue {
 	if n.isFloat || other.isFloat {
-		return numericValue{f: n.Float64() * other.Float64(), isFloat: true}
+		return floatValue(n.Float64() * other.Float64())
 	}
-	return numericValue{i: n.i * other.i, f: float64(n.i * other.i)}
+	return integerOp(n, other, mulInt, (*big.Int).Mul)
 }
 
 func (n numericValue) Div(other numericValue) numericValue {
-	return numericValue{f: n.Float64() / other.Float64(), isFloat: true}
+	return floatValue(n.Float64() / other.Float64())
+}
+
+// Pow stays an integer if both n and a non-negative exp are.
+func (n numericValue) Pow(exp numericValue) numericValue {
+	if n.isFloat || exp.isFloat || exp.bigInt().Sign() < 0 {
+		return floatValue(math.Pow(n.Float64(), exp.Float64()))
+	}
+	return bigValue(new(big.Int).Exp(n.bigInt(), exp.bigInt(), nil))
+}
+
+func (n numericValue) Abs() numericValue {
+	switch {
+	case n.isFloat:
+		return floatValue(math.Abs(n.f))
+	case n.big != nil:
+		return bigValue(new(big.Int).Abs(n.big))
+	case n.i < 0:
+		return intValue(0).Sub(n)
+	}
+	return n
+}
+
+func intValue(i int) numericValue {
+	return numericValue{i: i, f: float64(i)}
+}
+
+func floatValue(f float64) numericValue {
+	return numericValue{f: f, isFloat: true}
 }
 
 func procNumericFold(minArgs int, op func(numericValue, numericValue) numericValue) Proc {
@@ -142,76 +183,78 @@ func procNumericFold(minArgs int, op func(numericValue, numericValue) numericVal
 	}
 }
 
-func procNumericCmp(fn func(numericValue, numericValue) bool) Proc {
+// procCompare returns a proc taking ?options? a b that compares a and b with
+// the Collation given by the options and reports whether fn accepts the
+// result.
+func procCompare(fn func(int) bool) Proc {
 	return func(interp *Interp, args []*Token) (*Token, error) {
+		coll, args := parseCompareFlags(args)
 		if len(args) != 3 {
 			return EmptyToken, ErrArgCount(2, len(args)-1)
 		}
-
-		a, err := numericFromToken(args[1])
-		if err != nil {
-			return EmptyToken, err
-		}
-		b, err := numericFromToken(args[2])
-		if err != nil {
+		if err := coll.Check(args[1], args[2]); err != nil {
 			return EmptyToken, err
 		}
 
-		return NewToken(fn(a, b)), nil
+		return NewToken(fn(coll.Compare(args[1], args[2]))), nil
 	}
 }
 
-func strictIntFromToken(tok *Token) (int, error) {
-	switch v := tok.Data.(type) {
-	case int:
-		return v, nil
-	case Integer:
-		return v.Int(), nil
-	case float64:
-		return 0, fmt.Errorf("expected integer, got %q", tok.String)
-	case Floater:
-		return 0, fmt.Errorf("expected integer, got %q", tok.String)
+// integerFromToken is numericFromToken for integers only.
+func integerFromToken(tok *Token) (numericValue, error) {
+	n, err := numericFromToken(tok)
+	if err != nil || n.isFloat {
+		return numericValue{}, ErrExpectedInt(tok.String)
 	}
+	return n, nil
+}
 
-	i, err := strconv.Atoi(tok.String)
-	if err != nil {
-		return 0, fmt.Errorf("expected integer, got %q", tok.String)
+// strictIntFromToken is integerFromToken for integers that must fit in an
+// int.
+func strictIntFromToken(tok *Token) (int, error) {
+	n, err := integerFromToken(tok)
+	if err != nil || n.big != nil {
+		return 0, ErrExpectedInt(tok.String)
 	}
-	tok.Data = i
-	return i, nil
+	return n.i, nil
 }
 
-func procIntegerFold(minArgs int, op func(int, int) int) Proc {
+func procIntegerFold(minArgs int, small func(int, int) int, large func(z, x, y *big.Int) *big.Int) Proc {
 	return func(interp *Interp, args []*Token) (*Token, error) {
 		if len(args) < minArgs+1 {
 			return EmptyToken, ErrArgMinimum(minArgs, len(args)-1)
 		}
 
-		acc, err := strictIntFromToken(args[1])
+		acc, err := integerFromToken(args[1])
 		if err != nil {
 			return EmptyToken, err
 		}
 
 		for i := 2; i < len(args); i++ {
-			next, err := strictIntFromToken(args[i])
+			next, err := integerFromToken(args[i])
 			if err != nil {
 				return EmptyToken, err
 			}
-			acc = op(acc, next)
+			acc = integerOp(acc, next, exact(small), large)
 		}
 
-		return NewTokenInt(acc), nil
+		return acc.Token(), nil
 	}
 }
 
-// ProcEq performs a shallow comparison
+// ProcEq reports whether all its arguments are equal. Options -nocase,
+// -ascii, -numeric and -dictionary select the comparison; see Collation.
 func ProcEq(interp *Interp, args []*Token) (*Token, error) {
+	coll, args := parseCompareFlags(args)
 	if len(args) < 3 {
 		return EmptyToken, ErrArgMinimum(2, len(args)-1)
 	}
+	if err := coll.Check(args[1:]...); err != nil {
+		return EmptyToken, err
+	}
 
 	for i := 2; i < len(args); i++ {
-		if args[1].String != args[2].String {
+		if !coll.Equal(args[1], args[i]) {
 			return FalseToken, nil
 		}
 	}
@@ -219,17 +262,17 @@ func ProcEq(interp *Interp, args []*Token) (*Token, error) {
 	return TrueToken, nil
 }
 
-// ProcNeq performs a shallow comparison
+// ProcNeq is the opposite of ProcEq for two arguments.
 func ProcNeq(interp *Interp, args []*Token) (*Token, error) {
+	coll, args := parseCompareFlags(args)
 	if len(args) != 3 {
-		return EmptyToken, ErrArgCount(args[0], 1, len(args)-1)
+		return EmptyToken, ErrArgCount(2, len(args)-1)
 	}
-
-	if args[1].String == args[2].String {
-		return TrueToken, nil
+	if err := coll.Check(args[1], args[2]); err != nil {
+		return EmptyToken, err
 	}
 
-	return FalseToken, nil
+	return NewToken(!coll.Equal(args[1], args[2])), nil
 }
 
 // ProcNot performs a boolean not
@@ -343,15 +386,15 @@ func ProcIncr(interp *Interp, args []*Token) (*Token, error) {
 }
 
 func ProcBitAnd(interp *Interp, args []*Token) (*Token, error) {
-	return procIntegerFold(2, func(a, b int) int { return a & b })(interp, args)
+	return procIntegerFold(2, func(a, b int) int { return a & b }, (*big.Int).And)(interp, args)
 }
 
 func ProcBitOr(interp *Interp, args []*Token) (*Token, error) {
-	return procIntegerFold(2, func(a, b int) int { return a | b })(interp, args)
+	return procIntegerFold(2, func(a, b int) int { return a | b }, (*big.Int).Or)(interp, args)
 }
 
 func ProcBitXor(interp *Interp, args []*Token) (*Token, error) {
-	return procIntegerFold(2, func(a, b int) int { return a ^ b })(interp, args)
+	return procIntegerFold(2, func(a, b int) int { return a ^ b }, (*big.Int).Xor)(interp, args)
 }
 
 func ProcBitNot(interp *Interp, args []*Token) (*Token, error) {
@@ -359,43 +402,34 @@ func ProcBitNot(interp *Interp, args []*Token) (*Token, error) {
 		return EmptyToken, ErrArgCount(1, len(args)-1)
 	}
 
-	val, err := strictIntFromToken(args[1])
+	val, err := integerFromToken(args[1])
 	if err != nil {
 		return EmptyToken, err
 	}
-	return NewTokenInt(^val), nil
+	if val.big != nil {
+		return bigValue(new(big.Int).Not(val.big)).Token(), nil
+	}
+	return NewTokenInt(^val.i), nil
 }
 
 func ProcBitClear(interp *Interp, args []*Token) (*Token, error) {
-	return procIntegerFold(2, func(a, b int) int { return a &^ b })(interp, args)
+	return procIntegerFold(2, func(a, b int) int { return a &^ b }, (*big.Int).AndNot)(interp, args)
 }
 
 func ProcLeftShift(interp *Interp, args []*Token) (*Token, error) {
-	if len(args) != 3 {
-		return EmptyToken, ErrArgCount(2, len(args)-1)
-	}
-
-	lhs, err := strictIntFromToken(args[1])
-	if err != nil {
-		return EmptyToken, err
-	}
-	rhs, err := strictIntFromToken(args[2])
-	if err != nil {
-		return EmptyToken, err
-	}
-	if rhs < 0 {
-		return EmptyToken, fmt.Errorf("shift count must be non-negative")
-	}
-
-	return NewTokenInt(lhs << uint(rhs)), nil
+	return procShift(args, true)
 }
 
 func ProcRightShift(interp *Interp, args []*Token) (*Token, error) {
+	return procShift(args, false)
+}
+
+func procShift(args []*Token, left bool) (*Token, error) {
 	if len(args) != 3 {
 		return EmptyToken, ErrArgCount(2, len(args)-1)
 	}
 
-	lhs, err := strictIntFromToken(args[1])
+	lhs, err := integerFromToken(args[1])
 	if err != nil {
 		return EmptyToken, err
 	}
@@ -403,37 +437,31 @@ func ProcRightShift(interp *Interp, args []*Token) (*Token, error) {
 	if err != nil {
 		return EmptyToken, err
 	}
-	if rhs < 0 {
-		return EmptyToken, fmt.Errorf("shift count must be non-negative")
-	}
 
-	return NewTokenInt(lhs >> uint(rhs)), nil
+	n, err := shiftInt(lhs, rhs, left)
+	if err != nil {
+		return EmptyToken, err
+	}
+	return n.Token(), nil
 }
 
 func lessThan(a, b numericValue) bool {
 	if a.isFloat || b.isFloat {
 		return a.Float64() < b.Float64()
 	}
-	return a.i < b.i
-}
-
-func lessThanOrEqual(a, b numericValue) bool {
-	if a.isFloat || b.isFloat {
-		return a.Float64() <= b.Float64()
-	}
-	return a.i <= b.i
+	return compareInts(a, b) < 0
 }
 
 func greaterThan(a, b numericValue) bool {
 	if a.isFloat || b.isFloat {
 		return a.Float64() > b.Float64()
 	}
-	return a.i > b.i
+	return compareInts(a, b) > 0
 }
 
-func greaterThanOrEqual(a, b numericValue) bool {
-	if a.isFloat || b.isFloat {
-		return a.Float64() >= b.Float64()
+func compareInts(a, b numericValue) int {
+	if a.big == nil && b.big == nil {
+		return cmp.Compare(a.i, b.i)
 	}
-	return a.i >= b.i
+	return a.bigInt().Cmp(b.bigInt())
 }
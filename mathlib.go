@@ -0,0 +1,287 @@
+package adz
+
+import (
+	"math"
+	"math/big"
+	"math/rand/v2"
+)
+
+var (
+	MathLib = map[string]Proc{}
+	RandLib = map[string]Proc{}
+)
+
+func init() {
+	for name, fn := range mathFuncs {
+		MathLib[name] = procMathFunc(fn)
+	}
+
+	RandLib["seed"] = ProcRandSeed
+	RandLib["int"] = ProcRandInt
+	RandLib["float"] = ProcRandFloat
+	RandLib["choice"] = ProcRandChoice
+	RandLib["shuffle"] = ProcRandShuffle
+}
+
+// mathFunc is a function of the math namespace. The same functions are
+// callable from expr.
+type mathFunc struct {
+	arity int // -1 for one or more args
+	fn    func(args []numericValue) (numericValue, error)
+}
+
+func (mf mathFunc) call(args []numericValue) (numericValue, error) {
+	switch {
+	case mf.arity < 0 && len(args) == 0:
+		return numericValue{}, ErrArgMinimum(1, 0)
+	case mf.arity >= 0 && len(args) != mf.arity:
+		return numericValue{}, ErrArgCount(mf.arity, len(args))
+	}
+	return mf.fn(args)
+}
+
+// procMathFunc returns a proc calling fn. Procs taking any number of
+// arguments also accept them as lists, so [math::max {1 2} 3] is 3.
+func procMathFunc(fn mathFunc) Proc {
+	return func(interp *Interp, args []*Token) (*Token, error) {
+		var toks []*Token
+		if fn.arity < 0 {
+			for _, arg := range args[1:] {
+				list, err := arg.AsList()
+				if err != nil {
+					return EmptyToken, ErrExpectedList(arg.String)
+				}
+				toks = append(toks, list...)
+			}
+		} else {
+			toks = args[1:]
+		}
+
+		nums := make([]numericValue, len(toks))
+		for i, tok := range toks {
+			n, err := numericFromToken(tok)
+			if err != nil {
+				return EmptyToken, err
+			}
+			nums[i] = n
+		}
+		n, err := fn.call(nums)
+		if err != nil {
+			return EmptyToken, err
+		}
+		return n.Token(), nil
+	}
+}
+
+func mathConst(f float64) mathFunc {
+	return mathFunc{0, func([]numericValue) (numericValue, error) {
+		return floatValue(f), nil
+	}}
+}
+
+func mathFloat1(fn func(float64) float64) mathFunc {
+	return mathFunc{1, func(args []numericValue) (numericValue, error) {
+		return floatValue(fn(args[0].Float64())), nil
+	}}
+}
+
+func mathFloat2(fn func(float64, float64) float64) mathFunc {
+	return mathFunc{2, func(args []numericValue) (numericValue, error) {
+		return floatValue(fn(args[0].Float64(), args[1].Float64())), nil
+	}}
+}
+
+// mathRound applies fn to floats, returning an integer unless the result is
+// infinite or NaN. Integers are returned as is.
+func mathRound(fn func(float64) float64) mathFunc {
+	return mathFunc{1, func(args []numericValue) (numericValue, error) {
+		if !args[0].isFloat {
+			return args[0], nil
+		}
+		f := fn(args[0].f)
+		switch {
+		case math.IsInf(f, 0) || math.IsNaN(f):
+			return floatValue(f), nil
+		case f >= math.MinInt && f < math.MaxInt:
+			return intValue(int(f)), nil
+		}
+		b, _ := new(big.Float).SetFloat64(f).Int(nil)
+		return bigValue(b), nil
+	}}
+}
+
+// mathRem returns the remainder of the first argument divided by the
+// second. For integers it uses remInt, for floats floatFn.
+func mathRem(ieee bool, floatFn func(a, b float64) float64) mathFunc {
+	return mathFunc{2, func(args []numericValue) (numericValue, error) {
+		a, b := args[0], args[1]
+		if a.isFloat || b.isFloat {
+			return floatValue(floatFn(a.Float64(), b.Float64())), nil
+		}
+		return remInt(a, b, ieee)
+	}}
+}
+
+func mathExtreme(better func(a, b numericValue) bool) mathFunc {
+	return mathFunc{-1, func(args []numericValue) (numericValue, error) {
+		acc := args[0]
+		for _, n := range args[1:] {
+			if better(n, acc) {
+				acc = n
+			}
+		}
+		return acc, nil
+	}}
+}
+
+var mathFuncs = map[string]mathFunc{
+	"pi":  mathConst(math.Pi),
+	"e":   mathConst(math.E),
+	"inf": mathConst(math.Inf(1)),
+	"nan": mathConst(math.NaN()),
+
+	"sqrt":  mathFloat1(math.Sqrt),
+	"exp":   mathFloat1(math.Exp),
+	"log":   mathFloat1(math.Log),
+	"log2":  mathFloat1(math.Log2),
+	"log10": mathFloat1(math.Log10),
+	"sin":   mathFloat1(math.Sin),
+	"cos":   mathFloat1(math.Cos),
+	"tan":   mathFloat1(math.Tan),
+	"asin":  mathFloat1(math.Asin),
+	"acos":  mathFloat1(math.Acos),
+	"atan":  mathFloat1(math.Atan),
+	"sinh":  mathFloat1(math.Sinh),
+	"cosh":  mathFloat1(math.Cosh),
+	"tanh":  mathFloat1(math.Tanh),
+	"atan2": mathFloat2(math.Atan2),
+	"hypot": mathFloat2(math.Hypot),
+
+	"floor": mathRound(math.Floor),
+	"ceil":  mathRound(math.Ceil),
+	"round": mathRound(math.Round),
+	"trunc": mathRound(math.Trunc),
+
+	// mod truncates like Go's % and math.Mod; rem is the IEEE 754 remainder
+	// of math.Remainder.
+	"mod": mathRem(false, math.Mod),
+	"rem": mathRem(true, math.Remainder),
+
+	"pow": {2, func(args []numericValue) (numericValue, error) {
+		return args[0].Pow(args[1]), nil
+	}},
+	"abs": {1, func(args []numericValue) (numericValue, error) {
+		return args[0].Abs(), nil
+	}},
+	"min": mathExtreme(lessThan),
+	"max": mathExtreme(greaterThan),
+	"clamp": {3, func(args []numericValue) (numericValue, error) {
+		x, lo, hi := args[0], args[1], args[2]
+		if greaterThan(lo, hi) {
+			return numericValue{}, ErrCommand("clamp", "lower bound is greater than upper bound")
+		}
+		switch {
+		case lessThan(x, lo):
+			return lo, nil
+		case greaterThan(x, hi):
+			return hi, nil
+		}
+		return x, nil
+	}},
+}
+
+// rand returns the interp's random number generator.
+func (interp *Interp) rand() *rand.Rand {
+	return interp.rng
+}
+
+// math::rand::seed n
+func ProcRandSeed(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	seed, err := strictIntFromToken(args[1])
+	if err != nil {
+		return EmptyToken, err
+	}
+	interp.src.Seed(uint64(seed), 0)
+	return EmptyToken, nil
+}
+
+// math::rand::int ?min? max returns an integer in [min, max). min defaults
+// to 0.
+func ProcRandInt(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	var bounds [2]int
+	for i, arg := range args[1:] {
+		n, err := strictIntFromToken(arg)
+		if err != nil {
+			return EmptyToken, err
+		}
+		bounds[i] = n
+	}
+	lo, hi := 0, bounds[0]
+	if len(args) == 3 {
+		lo, hi = bounds[0], bounds[1]
+	}
+	if hi <= lo {
+		return EmptyToken, ErrCommand(args[0].String, "max must be greater than min")
+	}
+	// hi-lo can overflow an int, but not a uint64
+	return NewTokenInt(lo + int(interp.rand().Uint64N(uint64(hi)-uint64(lo)))), nil
+}
+
+// math::rand::float ?min? ?max? returns a float in [min, max). min defaults
+// to 0 and max to 1; with one argument, it's max.
+func ProcRandFloat(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) > 3 {
+		return EmptyToken, ErrArgCount(2, len(args)-1)
+	}
+	bounds := []float64{0, 1}
+	for i, arg := range args[1:] {
+		n, err := numericFromToken(arg)
+		if err != nil {
+			return EmptyToken, err
+		}
+		bounds[i] = n.Float64()
+	}
+	lo, hi := bounds[0], bounds[1]
+	if len(args) == 2 {
+		lo, hi = 0, bounds[0]
+	}
+	return NewToken(lo + interp.rand().Float64()*(hi-lo)), nil
+}
+
+// math::rand::choice list returns a random element of list.
+func ProcRandChoice(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	if len(list) == 0 {
+		return EmptyToken, ErrCommand(args[0].String, "empty list")
+	}
+	return list[interp.rand().IntN(len(list))], nil
+}
+
+// math::rand::shuffle list returns the elements of list in random order.
+func ProcRandShuffle(interp *Interp, args []*Token) (*Token, error) {
+	if len(args) != 2 {
+		return EmptyToken, ErrArgCount(1, len(args)-1)
+	}
+	list, err := args[1].AsList()
+	if err != nil {
+		return EmptyToken, ErrExpectedList(args[1].String)
+	}
+	shuffled := make([]*Token, len(list))
+	copy(shuffled, list)
+	interp.rand().Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return NewList(shuffled), nil
+}
@@ -0,0 +1,107 @@
+package adz
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMathLib(t *testing.T) {
+	interp := NewInterp()
+
+	runScripts(t, interp, []scriptTest{
+		{`math::sqrt 16`, "4"},
+		{`math::pow 2 10`, "1024"},
+		{`math::pow 2 0.5`, "1.4142135623730951"},
+		{`math::pow 2 -1`, "0.5"},
+		{`math::log10 1000`, "3"},
+		{`math::log2 8`, "3"},
+		{`math::log [math::e]`, "1"},
+		{`math::exp 0`, "1"},
+		{`math::cos 0`, "1"},
+		{`math::atan2 1 1`, "0.7853981633974483"},
+		{`math::floor 2.7`, "2"},
+		{`math::ceil 2.1`, "3"},
+		{`math::round -2.5`, "-3"},
+		{`math::trunc -2.7`, "-2"},
+		{`math::floor 5`, "5"},
+		{`math::floor [math::inf]`, "+Inf"},
+		{`math::abs -3`, "3"},
+		{`math::abs -3.5`, "3.5"},
+		{`math::min 3 1 2`, "1"},
+		{`math::max {3 1} 7 {2}`, "7"},
+		{`math::mod -7 3`, "-1"},
+		{`math::rem -7 3`, "-1"},
+		{`math::rem 7 4`, "-1"},
+		{`math::mod 7.5 2`, "1.5"},
+		{`math::hypot 3 4`, "5"},
+		{`math::clamp 15 0 10`, "10"},
+		{`math::clamp -1 0 10`, "0"},
+		{`math::clamp 2.5 0 10`, "2.5"},
+		{`math::pi`, "3.141592653589793"},
+		{`math::nan`, "NaN"},
+		{`expr {sqrt(9) + pow(2, 3) + floor(pi())}`, "14"},
+	})
+}
+
+func TestMathLib_Errors(t *testing.T) {
+	interp := NewInterp()
+	for _, tc := range []struct {
+		script string
+		want   error
+	}{
+		{`math::sqrt x`, ErrExpectedNumber},
+		{`math::mod 1 0`, ErrDivByZero},
+		{`math::sqrt 1 2`, ErrArgCount},
+		{`math::max`, ErrArgMinimum},
+		{`math::rand::int 1.5`, ErrExpectedInt},
+		{`math::rand::int 5 5`, ErrCommand},
+		{`math::rand::int -1`, ErrCommand},
+		{`expr {5 % 0}`, ErrDivByZero},
+	} {
+		_, err := interp.ExecString(tc.script)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.script, err, tc.want)
+		}
+	}
+}
+
+func TestMathRand(t *testing.T) {
+	interp := NewInterp()
+	run := func(script string) string {
+		out, err := interp.ExecString(script)
+		if err != nil {
+			t.Fatalf("%s: %v", script, err)
+		}
+		return out.String
+	}
+
+	const script = `list [math::rand::int 100] [math::rand::float] [math::rand::choice {a b c d}] [math::rand::shuffle {1 2 3 4 5}]`
+	run(`math::rand::seed 42`)
+	first := run(script)
+	run(`math::rand::seed 42`)
+	if again := run(script); again != first {
+		t.Errorf("same seed gave %q then %q", first, again)
+	}
+
+	for range 100 {
+		n, _ := NewTokenString(run(`math::rand::int 5 8`)).AsInt()
+		if n < 5 || n >= 8 {
+			t.Fatalf("rand::int 5 8 gave %d", n)
+		}
+		if n, err := NewTokenString(run(`math::rand::int -9223372036854775807 9223372036854775807`)).AsInt(); err != nil || n == 9223372036854775807 {
+			t.Fatalf("rand::int over the whole range gave %d, %v", n, err)
+		}
+		f, _ := NewTokenString(run(`math::rand::float 2 3`)).AsFloat()
+		if f < 2 || f >= 3 {
+			t.Fatalf("rand::float 2 3 gave %v", f)
+		}
+	}
+
+	shuffled := strings.Fields(run(`math::rand::shuffle {1 2 3 4 5}`))
+	slices.Sort(shuffled)
+	if strings.Join(shuffled, " ") != "1 2 3 4 5" {
+		t.Errorf("shuffle lost elements: %v", shuffled)
+	}
+}
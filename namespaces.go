@@ -57,6 +57,9 @@ func identifierParts(id string) (namespace, name string) {
 }
 
 func (interp *Interp) ResolveIdentifier(id string, create bool) (*Namespace, string, error) {
+	interp, leave := interp.enter()
+	defer leave()
+
 	// first strip a possible leading $
 	id = strings.TrimPrefix(id, "$")
 	if !isQualified(id) {
@@ -0,0 +1,181 @@
+package adz
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// parseNumber parses s as a numeric literal:
+//
+//	[+-]digits               decimal integer
+//	[+-]0x hexdigits         hexadecimal integer (also 0X)
+//	[+-]0o octaldigits       octal integer (also 0O)
+//	[+-]0b binarydigits      binary integer (also 0B)
+//	[+-]digits.digits[e[+-]digits]
+//	                         float; either side of the . may be empty, and
+//	                         1e9 is a float too
+//	[+-]Inf, [+-]NaN         infinities and not-a-number
+//
+// Underscores may separate digits, as in 1_000_000 or 0xFF_FF. Unlike Go, a
+// leading zero doesn't make a number octal. Surrounding whitespace is
+// ignored; anything else is an error. Integers that don't fit in an int are
+// returned as big integers.
+func parseNumber(s string) (numericValue, bool) {
+	s = strings.TrimSpace(s)
+	sign, body := "", s
+	if body != "" && (body[0] == '+' || body[0] == '-') {
+		sign, body = body[:1], body[1:]
+	}
+
+	base := 10
+	if len(body) > 2 && body[0] == '0' {
+		switch body[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			// as in Go, an underscore may follow the prefix
+			body = strings.TrimPrefix(body[2:], "_")
+		}
+	}
+
+	digits, ok := stripUnderscores(body)
+	if !ok || digits == "" {
+		return numericValue{}, false
+	}
+
+	if base == 10 && strings.Trim(digits, "0123456789") != "" {
+		// not all digits; the only other option is a float
+		if strings.ContainsAny(digits, "xXpP_") {
+			// ParseFloat would take hex floats and underscores
+			return numericValue{}, false
+		}
+		f, err := strconv.ParseFloat(sign+digits, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return numericValue{}, false
+		}
+		return floatValue(f), true
+	}
+
+	i, err := strconv.ParseInt(sign+digits, base, strconv.IntSize)
+	switch {
+	case err == nil:
+		return intValue(int(i)), true
+	case errors.Is(err, strconv.ErrRange):
+		b, ok := new(big.Int).SetString(sign+digits, base)
+		return numericValue{big: b}, ok
+	}
+	return numericValue{}, false
+}
+
+// stripUnderscores removes the underscores separating digits in s, failing
+// if any underscore isn't between two digits.
+func stripUnderscores(s string) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !isHex(s[i-1]) || !isHex(s[i+1])) {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(s, "_", ""), true
+}
+
+// bigValue returns b as a numericValue, using a plain int if it fits.
+func bigValue(b *big.Int) numericValue {
+	if b.IsInt64() && int64(int(b.Int64())) == b.Int64() {
+		return intValue(int(b.Int64()))
+	}
+	return numericValue{big: b}
+}
+
+// bigInt returns the integer n as a *big.Int.
+func (n numericValue) bigInt() *big.Int {
+	if n.big != nil {
+		return n.big
+	}
+	return big.NewInt(int64(n.i))
+}
+
+// integerOp applies small to two ints, or large to the big.Int form of a
+// and b if either is big or small reports overflow.
+func integerOp(a, b numericValue, small func(x, y int) (int, bool), large func(z, x, y *big.Int) *big.Int) numericValue {
+	if a.big == nil && b.big == nil {
+		if r, ok := small(a.i, b.i); ok {
+			return intValue(r)
+		}
+	}
+	return bigValue(large(new(big.Int), a.bigInt(), b.bigInt()))
+}
+
+func addInt(x, y int) (int, bool) {
+	s := x + y
+	return s, (s > x) == (y > 0)
+}
+
+func subInt(x, y int) (int, bool) {
+	d := x - y
+	return d, (d < x) == (y > 0)
+}
+
+func mulInt(x, y int) (int, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	p := x * y
+	return p, p/y == x && !(x == -1 && p == y) && !(y == -1 && p == x)
+}
+
+// exact adapts an operation that can't overflow for integerOp.
+func exact(op func(x, y int) int) func(x, y int) (int, bool) {
+	return func(x, y int) (int, bool) { return op(x, y), true }
+}
+
+// shiftInt shifts n by count bits, left if left is set. Left shifts that
+// overflow an int give a big integer.
+func shiftInt(n numericValue, count int, left bool) (numericValue, error) {
+	if count < 0 {
+		return numericValue{}, errors.New("shift count must be non-negative")
+	}
+	if n.big == nil {
+		switch {
+		case !left:
+			return intValue(n.i >> uint(count)), nil
+		case count < strconv.IntSize && (n.i<<uint(count))>>uint(count) == n.i:
+			return intValue(n.i << uint(count)), nil
+		}
+	}
+	if left {
+		return bigValue(new(big.Int).Lsh(n.bigInt(), uint(count))), nil
+	}
+	return bigValue(new(big.Int).Rsh(n.bigInt(), uint(count))), nil
+}
+
+// remInt returns the remainder of x/y for integers. With ieee set, the
+// quotient is rounded to the nearest integer, ties to even, as with
+// math.Remainder; otherwise it's truncated, as with Go's %.
+func remInt(x, y numericValue, ieee bool) (numericValue, error) {
+	if y.big == nil && y.i == 0 {
+		return numericValue{}, ErrDivByZero(x.Token().String)
+	}
+	q, r := new(big.Int).QuoRem(x.bigInt(), y.bigInt(), new(big.Int))
+	if ieee && r.Sign() != 0 {
+		absY := new(big.Int).Abs(y.bigInt())
+		twice := new(big.Int).Lsh(new(big.Int).Abs(r), 1)
+		if c := twice.Cmp(absY); c > 0 || (c == 0 && q.Bit(0) == 1) {
+			if r.Sign() > 0 {
+				r.Sub(r, absY)
+			} else {
+				r.Add(r, absY)
+			}
+		}
+	}
+	return bigValue(r), nil
+}
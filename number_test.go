@@ -0,0 +1,93 @@
+package adz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string // "" if not a number
+	}{
+		{"12", "12"},
+		{" -12 ", "-12"},
+		{"+7", "7"},
+		{"007", "7"},
+		{"1_000_000", "1000000"},
+		{"0x1F", "31"},
+		{"0XfF_fF", "65535"},
+		{"0x_10", "16"},
+		{"0o17", "15"},
+		{"-0b101", "-5"},
+		{"1.5", "1.5"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{"1e3", "1000"},
+		{"1.5E-3", "0.0015"},
+		{"1_000.25", "1000.25"},
+		{"-Inf", "-Inf"},
+		{"9223372036854775808", "9223372036854775808"},
+		{"-0x1_0000_0000_0000_0000", "-18446744073709551616"},
+		{"12abc", ""},
+		{"1__0", ""},
+		{"_1", ""},
+		{"1_", ""},
+		{"0x", ""},
+		{"0b102", ""},
+		{"0x1p3", ""},
+		{"1e", ""},
+		{"--1", ""},
+		{"", ""},
+		{"1 2", ""},
+	} {
+		n, ok := parseNumber(tc.in)
+		switch {
+		case tc.want == "" && ok:
+			t.Errorf("%q: expected error, got %s", tc.in, n.Token().String)
+		case tc.want != "" && !ok:
+			t.Errorf("%q: expected %s, got error", tc.in, tc.want)
+		case ok && n.Token().String != tc.want:
+			t.Errorf("%q: got %s, want %s", tc.in, n.Token().String, tc.want)
+		}
+	}
+}
+
+func TestAsInt_Strict(t *testing.T) {
+	for _, s := range []string{"12abc", "3.5", "99999999999999999999"} {
+		if _, err := NewTokenString(s).AsInt(); !errors.Is(err, ErrExpectedInt) {
+			t.Errorf("AsInt(%q): got %v, want ErrExpectedInt", s, err)
+		}
+	}
+	if i, err := NewTokenString("0b1010").AsInt(); err != nil || i != 10 {
+		t.Errorf("AsInt(0b1010): got %d, %v", i, err)
+	}
+	if f, err := NewTokenString("0x10").AsFloat(); err != nil || f != 16 {
+		t.Errorf("AsFloat(0x10): got %v, %v", f, err)
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	interp := NewInterp()
+	runScripts(t, interp, []scriptTest{
+		{`+ 9223372036854775807 1`, "9223372036854775808"},
+		{`- -9223372036854775808 1`, "-9223372036854775809"},
+		{`* 4294967296 4294967296`, "18446744073709551616"},
+		{`- 9223372036854775808 1`, "9223372036854775807"},
+		{`* -1 -9223372036854775808`, "9223372036854775808"},
+		{`<< 1 100`, "1267650600228229401496703205376"},
+		{`>> [<< 1 100] 99`, "2"},
+		{`& 18446744073709551615 0xFF`, "255"},
+		{`bitnot 18446744073709551616`, "-18446744073709551617"},
+		{`< 9223372036854775807 9223372036854775808`, "true"},
+		{`>= 18446744073709551616 1.5`, "true"},
+		{`set n 9223372036854775807; incr n; return $n`, "9223372036854775808"},
+		{`expr {2 ** 64}`, "18446744073709551616"},
+		{`expr {(1 << 70) % 1000}`, "424"},
+		{`expr {(1 << 70) | 1}`, "1180591620717411303425"},
+		{`math::abs -18446744073709551616`, "18446744073709551616"},
+		{`math::rem 18446744073709551617 2`, "1"},
+		{`math::floor 1e20`, "100000000000000000000"},
+		{`+ 0x10 0b11 0o7 1_000`, "1026"},
+	})
+}
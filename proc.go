@@ -45,6 +45,7 @@ func ProcMacro(interp *Interp, args []*Token) (*Token, error) {
 	ns.Procs[id] = func(pinterp *Interp, pargs []*Token) (*Token, error) {
 		return pinterp.ExecToken(parsedArgs["body"])
 	}
+	interp.dropCoroutine(ns.Qualified(id))
 
 	return parsedArgs["name"], nil
 }
@@ -59,14 +60,20 @@ func ProcProc(interp *Interp, args []*Token) (*Token, error) {
 		NewArgGroup(argArg, argBody),
 		NewArgGroup(nameArg, argArg, argBody),
 	)
+	as.Help = "Creates a proc, equivalent to a function in other languages. When called with 3 args, the proc is created with the name and (optionally) given namespace. When called with two, an anonymous proc is created, suitable for passing to something that expects a proc. The proc is named interpreter-wide, monotonically as proc#<int> where <int> is an ever increasing integer. Calling this proc will not work--anonymous procs must either be set to a variable or passed directly with [] to another command. Additional {arg} {body} pairs may follow the first; the proc is then overloaded and each call runs the body whose prototype matches the number of positional args given."
 
-	// use BindPosOnly so when we're specifying the named args of the proc to be created,
-	// they don't get parsed out as a flag to proc.
-	boundArgs, err := as.BindPosOnly(interp, args)
-	if err != nil {
-		as.Help = "Creates a proc, equivalent to a function in other languages. When called with 3 args, the proc is created with the name and (optionally) given namespace. When called with two, an anonymous proc is created, suitable for passing to something that expects a proc. The proc is named interpreter-wide, monotonically as proc#<int> where <int> is an ever increasing integer. Calling this proc will not work--anonymous procs must either be set to a variable or passed directly with [] to another command."
+	// proc takes an optional name followed by one or more arg/body pairs, so an
+	// odd number of args means a name was given. Everything is positional so when
+	// we're specifying the named args of the proc to be created, they don't get
+	// parsed out as a flag to proc.
+	if len(args) < 3 {
 		as.ShowUsage(interp.Stderr)
-		return EmptyToken, err
+		return EmptyToken, ErrArgMinimum(2, len(args)-1)
+	}
+	var name *Token
+	pairs := args[1:]
+	if len(pairs)%2 == 1 {
+		name, pairs = pairs[0], pairs[1:]
 	}
 
 	var (
@@ -84,15 +91,14 @@ func ProcProc(interp *Interp, args []*Token) (*Token, error) {
 		procPath string
 	)
 
-	name, ok := boundArgs["name"]
-
 	// default to current namespace
 	ns = interp.Frame.localNamespace
 	switch {
-	case !ok:
+	case name == nil:
 		// anonymous
 		procPath = interp.Monotonic.Next("proc")
 		procHome = nil
+		id = procPath
 	case isQualified(name.String):
 		// this is the fully qualified block
 		ns, id, _ = interp.ResolveIdentifier(name.String, true)
@@ -111,60 +117,141 @@ func ProcProc(interp *Interp, args []*Token) (*Token, error) {
 		procPath, id = name.String, name.String
 	}
 
-	procArgSet := NewArgSet(id)
-	err = procArgSet.ParseProto(boundArgs["arg"])
+	proc, err := newProc(ns, id, pairs, nil)
 	if err != nil {
 		return EmptyToken, err
 	}
 
-	proc := func(pinterp *Interp, pargs []*Token) (*Token, error) {
-		var pushed bool
+	if procHome != nil {
+		procHome[id] = proc
+		interp.dropCoroutine(procPath)
+	}
+	tok := NewTokenString(procPath)
+	tok.Data = Proc(proc)
+	return tok, nil
+}
+
+// procBody is a proc's body, parsed, or the error parsing it.
+type procBody struct {
+	source string
+	script Script
+	err    error
+}
 
-		for {
-			pBoundArgs, err := procArgSet.BindArgs(pinterp, pargs)
+// scriptFor returns the body for interp to run. Running a script caches
+// into its tokens, so interpreters started by go, which share procs with
+// the one that started them, lex a copy of their own.
+func (body procBody) scriptFor(interp *Interp) Script {
+	if interp.scripts == nil {
+		return body.script
+	}
+	script, ok := interp.scripts[body.source]
+	if !ok {
+		script, _ = LexString(body.source)
+		interp.scripts[body.source] = script
+	}
+	return script
+}
+
+// newProc returns a proc named id, running in namespace ns, from pairs of
+// argument prototypes and bodies. If capture is not nil, it's called with
+// the variables of each new frame once the arguments are bound, so it can
+// add variables of its own.
+func newProc(ns *Namespace, id string, pairs []*Token, capture func(vars map[string]*Token)) (Proc, error) {
+	// every ArgGroup of every prototype goes into a single ArgSet so arity
+	// dispatch and usage output come for free; bodies maps each group back
+	// to the body it was declared with, parsed up front.
+	procArgSet := NewArgSet(id)
+	bodies := make(map[*ArgGroup]procBody)
+	for i := 0; i < len(pairs); i += 2 {
+		protoSet := NewArgSet(id)
+		err := protoSet.ParseProto(pairs[i])
+		if err != nil {
+			return nil, fmt.Errorf("prototype %d: %w", i/2+1, err)
+		}
+		script, err := LexString(pairs[i+1].String)
+		for _, ag := range protoSet.ArgGroups {
+			bodies[ag] = procBody{pairs[i+1].String, script, err}
+		}
+		procArgSet.ArgGroup(protoSet.ArgGroups...)
+	}
+	if err := procArgSet.Validate(); err != nil {
+		return nil, err
+	}
+
+	return func(pinterp *Interp, pargs []*Token) (*Token, error) {
+		tail := pinterp.tailcalled(pargs)
+
+		ret, err := func() (*Token, error) {
+			pBoundArgs, ag, err := procArgSet.BindArgGroup(pinterp, pargs)
 			if err != nil {
 				procArgSet.ShowUsage(pinterp.Stderr)
 				return EmptyToken, err
 			}
-
-			if !pushed {
-				pinterp.Push(&Frame{
-					localNamespace: ns,
-					localProcs:     make(map[string]Proc),
-					localVars:      pBoundArgs,
-				})
-				defer pinterp.Pop()
-				pushed = true
+			if capture != nil {
+				capture(pBoundArgs)
 			}
 
-			pinterp.Frame.localVars = pBoundArgs
-
-			ret, err := pinterp.ExecToken(boundArgs["body"])
+			pinterp.Push(&Frame{
+				localNamespace: pinterp.sameNamespace(ns),
+				localProcs:     make(map[string]Proc),
+				localVars:      pBoundArgs,
+			})
+			defer pinterp.Pop()
 
-			switch err {
-			case ErrTailcall:
-				pargs, _ = ret.AsList()
-				reBoundArgs, err := procArgSet.BindArgs(pinterp, pargs)
-				if err != nil {
-					return EmptyToken, err
-				}
-				pinterp.Frame.localVars = reBoundArgs
-				continue
-
-			case ErrReturn:
-				err = nil
+			body := bodies[ag]
+			if body.err != nil {
+				return EmptyToken, body.err
 			}
+			return pinterp.ExecScript(body.scriptFor(pinterp))
+		}()
 
-			return ret, err
+		// the frame is gone by now, so a tailcall runs in its place
+		ret, err = pinterp.trampoline(ret, err, tail)
+		if err == ErrReturn {
+			err = nil
 		}
+
+		return ret, err
+	}, nil
+}
+
+// sameNamespace returns interp's namespace with the name of ns. They're
+// the same unless ns belongs to another interpreter that shares procs with
+// interp, as one started by go does.
+func (interp *Interp) sameNamespace(ns *Namespace) *Namespace {
+	if own, ok := interp.Namespaces[ns.Name]; ok {
+		return own
 	}
+	return ns
+}
 
-	if procHome != nil {
-		procHome[id] = proc
+// tailcalled reports whether the command args is being run by trampoline,
+// in place of a proc that used tailcall. Procs check this as they start:
+// if so, they pass any tailcall of their own back to that trampoline
+// rather than run one of their own.
+func (interp *Interp) tailcalled(args []*Token) bool {
+	if len(args) == 0 || interp.tailcall == nil || interp.tailcall != args[0] {
+		return false
 	}
-	tok := NewTokenString(procPath)
-	tok.Data = Proc(proc)
-	return tok, nil
+	interp.tailcall = nil
+	return true
+}
+
+// trampoline runs tailcalls. ret and err are the result of a proc's body,
+// after its frame has been popped; while err is ErrTailcall, ret is the
+// command to run in the proc's place. If tail is set, the proc was itself
+// run by a trampoline, so the tailcall is passed back for that one to run.
+// This way a chain of tailcalls, even between different procs, runs in
+// constant stack.
+func (interp *Interp) trampoline(ret *Token, err error, tail bool) (*Token, error) {
+	for err == ErrTailcall && !tail {
+		cmd, _ := ret.AsList()
+		interp.tailcall = cmd[0]
+		ret, err = interp.ExecLiteral(cmd)
+		interp.tailcall = nil
+	}
+	return ret, err
 }
 
 // ParseProto parses a proc argument prototype, returning the list of named args
@@ -155,3 +155,57 @@ func TestProc_AnonInNamespaceBlock_ExecutesInThatNs(t *testing.T) {
 		t.Fatalf("expected 42, got %q", out.String)
 	}
 }
+
+func TestProc_Overloaded_DispatchesByArity(t *testing.T) {
+	i := NewInterp()
+
+	out := mustRun(t, i, `proc area {r} {* 3 $r $r} {w h} {* $w $h}`)
+	if out.String != "::area" {
+		t.Fatalf("expected ::area, got %q", out.String)
+	}
+	out = mustRun(t, i, `list [area 2] [area 2 3]`)
+	if out.String != "12 6" {
+		t.Fatalf("expected {12 6}, got %q", out.String)
+	}
+
+	// anonymous procs can be overloaded too
+	out = mustRun(t, i, `[proc {a} {return one} {a b} {return two}] x y`)
+	if out.String != "two" {
+		t.Fatalf("expected two, got %q", out.String)
+	}
+}
+
+func TestProc_Overloaded_UsageListsEverySignature(t *testing.T) {
+	i := NewInterp()
+	stderr := &strings.Builder{}
+	i.Stderr = stderr
+
+	mustRun(t, i, `proc area {r} {* 3 $r $r} {w h} {* $w $h}`)
+	err := mustErr(t, i, `area 1 2 3`)
+	if !strings.Contains(err.Error(), "expected arity to be one of 1 | 2") {
+		t.Fatalf("expected arity error, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "area  r  |  w  h") {
+		t.Fatalf("expected usage to list both signatures, got %q", stderr.String())
+	}
+}
+
+func TestProc_Overloaded_DuplicateArityIsError(t *testing.T) {
+	i := NewInterp()
+	err := mustErr(t, i, `proc dup {a} {} {b} {}`)
+	if !strings.Contains(err.Error(), "duplicate fixed arity") {
+		t.Fatalf("expected duplicate arity error, got %v", err)
+	}
+}
+
+func TestProc_Overloaded_TailcallSwitchesOverload(t *testing.T) {
+	i := NewInterp()
+	mustRun(t, i, `proc sum {n} {tailcall sum $n 0} {n acc} {
+		if {== $n 0} {return $acc}
+		tailcall sum [- $n 1] [+ $acc $n]
+	}`)
+	out := mustRun(t, i, `sum 10`)
+	if out.String != "55" {
+		t.Fatalf("expected 55, got %q", out.String)
+	}
+}
@@ -0,0 +1,250 @@
+package adz
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	StdLib["regexp"] = ProcRegexp
+	StdLib["regsub"] = ProcRegsub
+}
+
+// AsRegexp compiles tok as a regular expression in the syntax of Go's regexp
+// package, case-insensitively if nocase is set. The compiled expression is
+// cached in tok's Data, so a pattern kept in a variable, or braced in a
+// script, is only compiled once.
+func (tok *Token) AsRegexp(nocase bool) (*regexp.Regexp, error) {
+	src := tok.String
+	if nocase {
+		src = "(?i)" + src
+	}
+	re, ok := tok.Data.(*regexp.Regexp)
+	if ok && re.String() == src {
+		return re, nil
+	}
+	re, err := regexp.Compile(src)
+	if err != nil {
+		return nil, ErrSyntax(err.Error())
+	}
+	if tok.Data == nil || ok {
+		tok.Data = re
+	}
+	return re, nil
+}
+
+// stringMatcher returns a function reporting whether a string matches
+// pattern, which is interpreted according to style: glob (as with
+// filepath.Match), regex or substr.
+func stringMatcher(style string, pattern *Token, matchcase bool) (func(string) bool, error) {
+	fold := func(s string) string { return s }
+	if !matchcase {
+		fold = strings.ToLower
+	}
+
+	switch style {
+	case "glob":
+		pat := fold(pattern.String)
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("glob %s: %w", pattern.Quoted(), err)
+		}
+		return func(s string) bool {
+			m, _ := filepath.Match(pat, fold(s))
+			return m
+		}, nil
+	case "regex":
+		re, err := pattern.AsRegexp(!matchcase)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case "substr":
+		pat := fold(pattern.String)
+		return func(s string) bool {
+			return strings.Contains(fold(s), pat)
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid match style: %s", style)
+}
+
+// submatchTokens returns the text of each submatch of str given by the
+// indices m, as from FindStringSubmatchIndex, or if indices is set, the
+// inclusive {start end} byte offsets of each, {-1 -1} for groups that
+// didn't match.
+func submatchTokens(str string, m []int, indices bool) []*Token {
+	toks := make([]*Token, len(m)/2)
+	for i := range toks {
+		start, end := m[2*i], m[2*i+1]
+		switch {
+		case indices && start < 0:
+			toks[i] = NewList([]*Token{NewTokenInt(-1), NewTokenInt(-1)})
+		case indices:
+			toks[i] = NewList([]*Token{NewTokenInt(start), NewTokenInt(end - 1)})
+		case start < 0:
+			toks[i] = EmptyToken
+		default:
+			toks[i] = NewTokenString(str[start:end])
+		}
+	}
+	return toks
+}
+
+func ProcRegexp(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		&Argument{Name: "-nocase", Switch: true, Help: "Match case-insensitively."},
+		&Argument{Name: "-all", Switch: true, Help: "Match as many times as possible, returning the number of matches. Variables are set from the last match."},
+		&Argument{Name: "-indices", Switch: true, Help: "Give the start and end byte offsets, inclusive, of each match and group instead of the matched text; groups that didn't match give {-1 -1}."},
+		&Argument{Name: "-inline", Switch: true, Help: "Return the match and its groups as a list instead of setting variables. With -all, the lists of every match are concatenated."},
+		&Argument{
+			Name:    "-groupvar",
+			Help:    "Set this variable to a dict of the pattern's named groups, (?P<name>...), from the last match.",
+			Default: EmptyToken,
+		},
+		ArgHelp("pattern", "A regular expression, in the syntax of Go's regexp package."),
+		ArgHelp("string", "The string to match against."),
+		ArgHelp("args", "Variables to set to the matched text and then to each group."),
+	)
+	as.Help = "Matches {pattern} against {string}, returning whether it matched."
+
+	parsedArgs, err := as.BindNamedFirst(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+	all := parsedArgs["all"].Data.(bool)
+	indices := parsedArgs["indices"].Data.(bool)
+	inline := parsedArgs["inline"].Data.(bool)
+	vars, _ := parsedArgs["args"].AsList()
+	if inline && len(vars) > 0 {
+		return EmptyToken, ErrCommand(args[0].String, "-inline can't be used with match variables")
+	}
+
+	re, err := parsedArgs["pattern"].AsRegexp(parsedArgs["nocase"].Data.(bool))
+	if err != nil {
+		return EmptyToken, err
+	}
+	str := parsedArgs["string"].String
+
+	n := 1
+	if all {
+		n = -1
+	}
+	matches := re.FindAllStringSubmatchIndex(str, n)
+
+	if inline {
+		out := []*Token{}
+		for _, m := range matches {
+			out = append(out, submatchTokens(str, m, indices)...)
+		}
+		return NewList(out), nil
+	}
+
+	if len(matches) > 0 {
+		last := submatchTokens(str, matches[len(matches)-1], indices)
+		for i, v := range vars {
+			val := EmptyToken
+			if i < len(last) {
+				val = last[i]
+			}
+			if _, err := interp.SetVar(v.String, val); err != nil {
+				return EmptyToken, err
+			}
+		}
+		if name := parsedArgs["groupvar"].String; name != "" {
+			d := NewDict()
+			for i, group := range re.SubexpNames() {
+				if group != "" {
+					d.Set(group, last[i])
+				}
+			}
+			if _, err := interp.SetVar(name, d.Token()); err != nil {
+				return EmptyToken, err
+			}
+		}
+	}
+
+	if all {
+		return NewTokenInt(len(matches)), nil
+	}
+	if len(matches) > 0 {
+		return TrueToken, nil
+	}
+	return FalseToken, nil
+}
+
+func ProcRegsub(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		&Argument{Name: "-nocase", Switch: true, Help: "Match case-insensitively."},
+		&Argument{Name: "-all", Switch: true, Help: "Replace every match rather than just the first."},
+		&Argument{Name: "-command", Switch: true, Help: "Treat {replacement} as a command; it's called with the matched text and each group appended and its result replaces the match."},
+		ArgHelp("pattern", "A regular expression, in the syntax of Go's regexp package."),
+		ArgHelp("string", "The string to substitute in."),
+		ArgHelp("replacement", "The replacement text; $1 or ${name} are replaced by the text of a group, and $$ by a dollar sign."),
+		ArgDefault("varName", EmptyToken),
+	)
+	as.Help = "Returns {string} with matches of {pattern} replaced by {replacement}. " +
+		"If {varName} is given, the result is stored in it instead and the number of replacements is returned."
+
+	parsedArgs, err := as.BindNamedFirst(interp, args)
+	if err != nil {
+		as.ShowUsage(interp.Stderr)
+		return EmptyToken, err
+	}
+
+	re, err := parsedArgs["pattern"].AsRegexp(parsedArgs["nocase"].Data.(bool))
+	if err != nil {
+		return EmptyToken, err
+	}
+	str := parsedArgs["string"].String
+	repl := parsedArgs["replacement"]
+
+	var cmdPrefix Command
+	if parsedArgs["command"].Data.(bool) {
+		if cmdPrefix, err = repl.AsList(); err != nil {
+			return EmptyToken, ErrExpectedList(repl.String)
+		}
+	}
+
+	n := 1
+	if parsedArgs["all"].Data.(bool) {
+		n = -1
+	}
+	matches := re.FindAllStringSubmatchIndex(str, n)
+
+	var out []byte
+	prev := 0
+	for _, m := range matches {
+		out = append(out, str[prev:m[0]]...)
+		prev = m[1]
+		if cmdPrefix == nil {
+			out = re.ExpandString(out, repl.String, str, m)
+			continue
+		}
+		cmd := slices.Clone(cmdPrefix)
+		for i := 0; i < len(m); i += 2 {
+			if m[i] < 0 {
+				cmd = append(cmd, EmptyToken)
+			} else {
+				cmd = append(cmd, NewTokenString(str[m[i]:m[i+1]]))
+			}
+		}
+		// use ExecLiteral so the matched text doesn't get re-interpretted
+		ret, err := interp.ExecLiteral(cmd)
+		if err != nil {
+			return EmptyToken, err
+		}
+		out = append(out, ret.String...)
+	}
+	out = append(out, str[prev:]...)
+
+	if name := parsedArgs["varName"].String; name != "" {
+		if _, err := interp.SetVar(name, NewTokenString(string(out))); err != nil {
+			return EmptyToken, err
+		}
+		return NewTokenInt(len(matches)), nil
+	}
+	return NewTokenString(string(out)), nil
+}
@@ -0,0 +1,112 @@
+package adz
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRegexp(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		{`regexp {b+} abbbc`, "true"},
+		{`regexp {x} abc`, "false"},
+		{`regexp {(\w+)@(\w+)} {mail bob@example now} m user host; list $m $user $host`, "bob@example bob example"},
+		{`regexp {(a)|(b)} b m x y; list $m $x $y`, "b {} b"},
+		{`regexp -nocase {ABC} xabcx`, "true"},
+		{`regexp -all {\d+} {1 22 333}`, "3"},
+		{`regexp -all {\d+} {1 22 333} m; return $m`, "333"},
+		{`regexp -indices {b+} abbbc m; return $m`, "1 3"},
+		{`regexp -indices {(x)?b} abc m g; return $g`, "-1 -1"},
+		{`regexp -inline {(\w)(\d)} {a1 b2}`, "a1 a 1"},
+		{`regexp -all -inline {(\w)(\d)} {a1 b2}`, "a1 a 1 b2 b 2"},
+		{`regexp -inline {z} abc`, ""},
+		{`regexp -groupvar g {(?P<year>\d{4})-(?P<month>\d\d)} 2024-06; return $g`, "year 2024 month 06"},
+		{`regexp -- {-\d} a-1`, "true"},
+		{`regsub {o} foo 0`, "f0o"},
+		{`regsub -all {o} foo 0`, "f00"},
+		{`regsub -all {(\w+)=(\w+)} {a=1 b=2} {$2=$1}`, "1=a 2=b"},
+		{`regsub -all {(?P<k>\w+)=\w+} {a=1 b=2} {${k}}`, "a b"},
+		{`regsub -nocase -all {X} xXx y`, "yyy"},
+		{`regsub -all -command {\d+} {a1 b22} {+ 1}`, "a2 b23"},
+		{`proc swap {m a b} { return $b$a }; regsub -all -command {(\w)(\d)} {a1 b2} swap`, "1a 2b"},
+		{`regsub -all {o} foo 0 out; list $out`, "f00"},
+		{`regsub -all {o} foo 0 out`, "2"},
+		{`regsub {z} foo 0`, "foo"},
+		// only arguments before the pattern are options
+		{`regexp {\d+} -5 m; return $m`, "5"},
+		{`regexp -inline -- {-\d+} {x -12 y}`, "-12"},
+		{`regexp -nocase {^-x$} -X`, "true"},
+		{`regsub x -x y`, "-y"},
+		{`regsub -all {\d} -1-2 -n`, "--n--n"},
+		{`regsub {a} abc -1 out; return $out`, "-1bc"},
+		{`list::find -type regex {apple banana cherry} {an}`, "banana"},
+		{`list::find -type regex -matchcase false {Apple banana} {^a}`, "Apple"},
+		{`list::find -type glob {apple banana} {b*}`, "banana"},
+		{`match {a*} abc`, "true"},
+		{`match -style regex {^a.c$} abc`, "true"},
+		{`match -style substr -matchcase false {BC} abc`, "true"},
+		{`match -style substr {x} abc`, "false"},
+	})
+}
+
+func TestRegexp_Cached(t *testing.T) {
+	interp := NewInterp()
+	if _, err := interp.ExecString(`set pat {a+}; regexp $pat baa`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pat, _ := interp.GetVar("pat")
+	re, ok := pat.Data.(*regexp.Regexp)
+	if !ok {
+		t.Fatalf("expected pattern to be cached, got %T", pat.Data)
+	}
+	if _, err := interp.ExecString(`regexp $pat baa`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pat.Data.(*regexp.Regexp) != re {
+		t.Error("expected cached pattern to be reused")
+	}
+	if _, err := interp.ExecString(`regexp -nocase $pat BAA`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pat.Data.(*regexp.Regexp).String() != "(?i)a+" {
+		t.Errorf("expected -nocase to recompile, got %v", pat.Data)
+	}
+}
+
+func TestRegexp_CachedBraced(t *testing.T) {
+	interp := NewInterp()
+	script, err := LexString(`regexp {b+} abbbc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first *regexp.Regexp
+	for i := range 3 {
+		if _, err := interp.ExecScript(script); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lit, ok := script[0][1].Data.(*Token)
+		if !ok {
+			t.Fatalf("expected braced word to be cached, got %T", script[0][1].Data)
+		}
+		re, ok := lit.Data.(*regexp.Regexp)
+		if !ok {
+			t.Fatalf("expected pattern to be cached, got %T", lit.Data)
+		}
+		if first == nil {
+			first = re
+		} else if re != first {
+			t.Errorf("run %d compiled the pattern again", i+1)
+		}
+	}
+}
+
+func TestRegexp_Errors(t *testing.T) {
+	interp := NewInterp()
+	runScriptErrors(t, interp, []scriptTest{
+		{`regexp {(} abc`, "missing closing )"},
+		{`regsub {(} abc x`, "missing closing )"},
+		{`regexp -inline a abc m`, "-inline"},
+		{`match -style regex {[} abc`, "missing closing ]"},
+		{`match -style glob {[} abc`, "syntax error in pattern"},
+		{`match -style nope a a`, "nope"},
+	})
+}
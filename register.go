@@ -0,0 +1,197 @@
+package adz
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+type funcConfig struct {
+	help     string
+	names    []string
+	argHelp  map[string]string
+	defaults map[string]*Token
+	ctx      context.Context
+}
+
+// FuncOption configures how RegisterFunc and FuncProc expose a Go function.
+type FuncOption func(*funcConfig)
+
+// FuncHelp sets the help text shown in the proc's usage.
+func FuncHelp(help string) FuncOption {
+	return func(fc *funcConfig) { fc.help = help }
+}
+
+// FuncArgNames names the function's parameters, in order. A context.Context
+// first parameter is not counted. Unnamed parameters default to arg1, arg2...
+// and an unnamed variadic parameter to args.
+func FuncArgNames(names ...string) FuncOption {
+	return func(fc *funcConfig) { fc.names = names }
+}
+
+// FuncArgHelp sets the help text of the parameter called name.
+func FuncArgHelp(name, help string) FuncOption {
+	return func(fc *funcConfig) { fc.argHelp[name] = help }
+}
+
+// FuncArgDefault gives the parameter called name a default value, making it
+// optional. Only trailing parameters can usefully have defaults.
+func FuncArgDefault(name string, def *Token) FuncOption {
+	return func(fc *funcConfig) { fc.defaults[name] = def }
+}
+
+// FuncContext sets the context passed to functions whose first parameter is a
+// context.Context. The default is context.Background().
+func FuncContext(ctx context.Context) FuncOption {
+	return func(fc *funcConfig) { fc.ctx = ctx }
+}
+
+// RegisterFunc exposes the Go function fn as the proc name. See FuncProc.
+func (interp *Interp) RegisterFunc(name string, fn any, opts ...FuncOption) error {
+	interp, leave := interp.enter()
+	defer leave()
+
+	proc, err := FuncProc(name, fn, opts...)
+	if err != nil {
+		return err
+	}
+	return interp.Proc(name, proc)
+}
+
+// FuncProc uses reflection to build a Proc that calls fn. An ArgSet is built
+// from fn's parameters so usage and arity checking work as they do for any
+// other proc, and each argument is converted to the parameter's type. A
+// context.Context first parameter is filled in rather than taken from the
+// args. If fn's last return value is an error, it becomes the proc's error;
+// the remaining return values are returned as a single token or, if there
+// are several, a list.
+func FuncProc(name string, fn any, opts ...FuncOption) (Proc, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("%s: expected a func, got %T", name, fn)
+	}
+	ft := fv.Type()
+
+	cfg := &funcConfig{
+		argHelp:  make(map[string]string),
+		defaults: make(map[string]*Token),
+		ctx:      context.Background(),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	first := 0
+	if ft.NumIn() > 0 && ft.In(0) == contextType {
+		first = 1
+	}
+
+	// paramTypes[i] is the type bound to the i'th positional argument;
+	// for a variadic func the last entry is the element type.
+	paramTypes := make([]reflect.Type, 0, ft.NumIn()-first)
+	args := make([]*Argument, 0, ft.NumIn()-first)
+	for i := first; i < ft.NumIn(); i++ {
+		pt := ft.In(i)
+		argName := fmt.Sprintf("arg%d", i-first+1)
+		variadic := ft.IsVariadic() && i == ft.NumIn()-1
+		if variadic {
+			pt = pt.Elem()
+			argName = "args"
+		}
+		if i-first < len(cfg.names) {
+			argName = cfg.names[i-first]
+		}
+		help, ok := cfg.argHelp[argName]
+		if !ok {
+			help = pt.String()
+		}
+		def := cfg.defaults[argName]
+		if variadic && def == nil {
+			def = EmptyToken
+		}
+		paramTypes = append(paramTypes, pt)
+		args = append(args, &Argument{
+			Name:    argName,
+			Default: def,
+			Help:    help,
+		})
+	}
+
+	as := NewArgSet(name, args...)
+	as.Help = cfg.help
+	if len(args) == 0 {
+		as.ArgGroup(NewArgGroup())
+	}
+	if ft.IsVariadic() {
+		// the variadic parameter takes the rest of the args, whatever its name
+		as.ArgGroups[0].PosVariadic = true
+	}
+	if err := as.Validate(); err != nil {
+		return nil, err
+	}
+
+	return func(interp *Interp, argv []*Token) (*Token, error) {
+		// positional only, so negative numbers aren't mistaken for named args
+		bound, err := as.BindPosOnly(interp, argv)
+		if err != nil {
+			as.ShowUsage(interp.Stderr)
+			return EmptyToken, err
+		}
+
+		in := make([]reflect.Value, 0, ft.NumIn())
+		if first == 1 {
+			in = append(in, reflect.ValueOf(cfg.ctx))
+		}
+		for i, arg := range args {
+			if ft.IsVariadic() && i == len(args)-1 {
+				tail, _ := bound[arg.Name].AsList()
+				for j := range tail {
+					val, err := tokenToValue(interp, tail[j], paramTypes[i])
+					if err != nil {
+						return EmptyToken, fmt.Errorf("argument {%s}, element %d: %w", arg.Name, j, err)
+					}
+					in = append(in, val)
+				}
+				break
+			}
+			val, err := tokenToValue(interp, bound[arg.Name], paramTypes[i])
+			if err != nil {
+				return EmptyToken, fmt.Errorf("argument {%s}: %w", arg.Name, err)
+			}
+			in = append(in, val)
+		}
+
+		return callResults(fv.Call(in))
+	}, nil
+}
+
+// TokenData returns tok's Data as a T, unwrapping it first if it implements
+// Interfacer. It is a plain type assertion, so code generated by adzbind uses
+// it to recover Go values from tokens without reflection.
+func TokenData[T any](tok *Token) (T, error) {
+	if v, ok := tok.Data.(T); ok {
+		return v, nil
+	}
+	if ier, ok := tok.Data.(Interfacer); ok {
+		if v, ok := ier.Interface().(T); ok {
+			return v, nil
+		}
+	}
+	var zero T
+	return zero, fmt.Errorf("expected %s, got %T", reflect.TypeOf((*T)(nil)).Elem(), tok.Data)
+}
+
+// TokenDataPtr is like TokenData, but accepts Data holding either a *T or a T,
+// always returning a *T. This suits method receivers.
+func TokenDataPtr[T any](tok *Token) (*T, error) {
+	if p, err := TokenData[*T](tok); err == nil {
+		return p, nil
+	}
+	v, err := TokenData[T](tok)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
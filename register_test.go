@@ -0,0 +1,154 @@
+package adz
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFunc_Basic(t *testing.T) {
+	interp := NewInterp()
+	err := interp.RegisterFunc("add", func(a, b int) int { return a + b })
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := interp.ExecString(`add -5 3`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "-2" {
+		t.Errorf("expected -2, got %s", out.String)
+	}
+
+	_, err = interp.ExecString(`add x 3`)
+	if err == nil || !strings.Contains(err.Error(), "arg1") {
+		t.Errorf("expected conversion error naming arg1, got %v", err)
+	}
+
+	_, err = interp.ExecString(`add 1`)
+	if err == nil {
+		t.Errorf("expected arg count error, got nil")
+	}
+}
+
+func TestRegisterFunc_Variadic(t *testing.T) {
+	interp := NewInterp()
+	interp.RegisterFunc("join", strings.Join, FuncArgNames("elems", "sep"))
+	interp.RegisterFunc("sum", func(prefix string, nums ...float64) string {
+		var acc float64
+		for _, n := range nums {
+			acc += n
+		}
+		return prefix + NewToken(acc).String
+	})
+
+	out, err := interp.ExecString(`join {a b c} -`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "a-b-c" {
+		t.Errorf("expected a-b-c, got %s", out.String)
+	}
+
+	out, err = interp.ExecString(`sum total: 1 2.5 3`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "total:6.5" {
+		t.Errorf("expected total:6.5, got %s", out.String)
+	}
+
+	out, err = interp.ExecString(`sum none`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "none0" {
+		t.Errorf("expected none0, got %s", out.String)
+	}
+
+	_, err = interp.ExecString(`sum total: 1 x`)
+	if err == nil || !strings.Contains(err.Error(), "argument {args}") {
+		t.Errorf("expected conversion error naming {args}, got %v", err)
+	}
+
+	interp.RegisterFunc("max", func(first int, rest ...int) int {
+		for _, n := range rest {
+			first = max(first, n)
+		}
+		return first
+	}, FuncArgNames("first", "nums"))
+
+	out, err = interp.ExecString(`max 3 -7 9 4`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "9" {
+		t.Errorf("expected 9, got %s", out.String)
+	}
+
+	out, err = interp.ExecString(`max 3`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "3" {
+		t.Errorf("expected 3, got %s", out.String)
+	}
+
+	_, err = interp.ExecString(`max 1 x`)
+	if err == nil || !strings.Contains(err.Error(), "argument {nums}") {
+		t.Errorf("expected conversion error naming {nums}, got %v", err)
+	}
+}
+
+func TestRegisterFunc_ContextAndError(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "hello")
+	errNeg := errors.New("negative")
+
+	interp := NewInterp()
+	interp.RegisterFunc("greet", func(ctx context.Context, n int) (string, error) {
+		if n < 0 {
+			return "", errNeg
+		}
+		return strings.Repeat(ctx.Value(ctxKey{}).(string), n), nil
+	},
+		FuncContext(ctx),
+		FuncArgNames("count"),
+		FuncArgDefault("count", NewToken(1)),
+		FuncArgHelp("count", "times to greet"),
+		FuncHelp("greet says hello"),
+	)
+
+	out, err := interp.ExecString(`list [greet] [greet 2]`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "hello hellohello" {
+		t.Errorf("expected {hello hellohello}, got %s", out.String)
+	}
+
+	_, err = interp.ExecString(`greet -1`)
+	if !errors.Is(err, errNeg) {
+		t.Errorf("expected errNeg, got %v", err)
+	}
+}
+
+func TestRegisterFunc_MultipleReturns(t *testing.T) {
+	interp := NewInterp()
+	interp.RegisterFunc("divmod", func(a, b int) (int, int) { return a / b, a % b })
+	out, err := interp.ExecString(`divmod 17 5`)
+	if err != nil {
+		t.Fatalf("expected nil err, got %s", err)
+	}
+	if out.String != "3 2" {
+		t.Errorf("expected {3 2}, got %s", out.String)
+	}
+}
+
+func TestRegisterFunc_NotAFunc(t *testing.T) {
+	interp := NewInterp()
+	if err := interp.RegisterFunc("nope", 42); err == nil {
+		t.Errorf("expected error registering a non-func")
+	}
+}
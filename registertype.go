@@ -0,0 +1,138 @@
+package adz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type typeConfig struct {
+	help       string
+	methodSigs map[string]*ArgSet
+	fieldHelp  map[string]string
+	wrapOpts   []WrapOption
+}
+
+// TypeOption configures how RegisterType exposes a Go type.
+type TypeOption func(*typeConfig)
+
+// TypeHelp sets the description of the type, shown by its help.
+func TypeHelp(help string) TypeOption {
+	return func(tc *typeConfig) { tc.help = help }
+}
+
+// TypeMethod gives the method called name an ArgSet. Its arguments are
+// validated against the ArgSet before each call and its help is shown by
+// "$obj help name".
+func TypeMethod(name string, as *ArgSet) TypeOption {
+	return func(tc *typeConfig) { tc.methodSigs[name] = as }
+}
+
+// TypeFieldHelp documents the field called name.
+func TypeFieldHelp(name, help string) TypeOption {
+	return func(tc *typeConfig) { tc.fieldHelp[name] = help }
+}
+
+// TypeWrapOptions sets the WrapOptions used for every instance.
+func TypeWrapOptions(opts ...WrapOption) TypeOption {
+	return func(tc *typeConfig) { tc.wrapOpts = append(tc.wrapOpts, opts...) }
+}
+
+// RegisterType adds the proc name, which lets scripts create and inspect
+// values of type T:
+//
+//	name new ?-field value ...?  ;# new instance, fields set by path
+//	name fromjson json           ;# new instance decoded from JSON
+//	name fields                  ;# list of field names
+//	name methods                 ;# list of method names
+//	name help                    ;# methods and fields, with their docs
+//
+// Instances are GoObject tokens, as returned by WrapObject, whose string is a
+// handle unique to each, such as name#0.
+func RegisterType[T any](interp *Interp, name string, opts ...TypeOption) error {
+	return interp.Proc(name, TypeProc[T](name, opts...))
+}
+
+// TypeProc returns the proc RegisterType adds.
+func TypeProc[T any](name string, opts ...TypeOption) Proc {
+	cfg := &typeConfig{
+		methodSigs: make(map[string]*ArgSet),
+		fieldHelp:  make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	wrapOpts := append([]WrapOption{
+		WithHelp(cfg.help),
+		WithFieldHelp(cfg.fieldHelp),
+	}, cfg.wrapOpts...)
+
+	typ := typeOfT[T]()
+	info := typeInfoOf(reflect.PointerTo(typ))
+
+	usage := fmt.Sprintf("%s new ?-field value ...? | fromjson json | fields | methods | help", name)
+
+	newObject := func() *GoObject {
+		tok := WrapObject(reflect.New(typ).Interface(), cfg.methodSigs, wrapOpts...)
+		return tok.Data.(*GoObject)
+	}
+	// each instance gets a handle of its own, as class instances do, so
+	// that one whose Data is lost isn't taken for the type's command
+	asToken := func(interp *Interp, g *GoObject) *Token {
+		return &Token{String: interp.Monotonic.Next(name), Data: Procer(g)}
+	}
+
+	return func(interp *Interp, args []*Token) (*Token, error) {
+		if len(args) < 2 {
+			fmt.Fprintln(interp.Stderr, usage)
+			return EmptyToken, ErrArgMinimum(1, 0)
+		}
+
+		switch args[1].String {
+		case "new":
+			if len(args)%2 != 0 {
+				return EmptyToken, fmt.Errorf("%s new: expected -field value pairs", name)
+			}
+			g := newObject()
+			for i := 2; i < len(args); i += 2 {
+				field := strings.TrimPrefix(args[i].String, "-")
+				if _, err := g.setPath(interp, field, args[i+1]); err != nil {
+					return EmptyToken, fmt.Errorf("%s new: field %s: %w", name, field, err)
+				}
+			}
+			return asToken(interp, g), nil
+
+		case "fromjson":
+			if len(args) != 3 {
+				return EmptyToken, ErrArgCount(1, len(args)-2)
+			}
+			norm, kind := InferJSON([]byte(args[2].String))
+			if kind != jsonObj {
+				return EmptyToken, fmt.Errorf("%s fromjson: expected a JSON object", name)
+			}
+			g := newObject()
+			dec := json.NewDecoder(bytes.NewReader(norm))
+			if g.StrictJSON {
+				dec.DisallowUnknownFields()
+			}
+			if err := dec.Decode(g.Interface()); err != nil {
+				return EmptyToken, fmt.Errorf("%s fromjson: %w", name, err)
+			}
+			return asToken(interp, g), nil
+
+		case "fields":
+			return NewList(NewTokenListString(info.fieldNames)), nil
+
+		case "methods":
+			return NewList(NewTokenListString(info.methodNames)), nil
+
+		case "help":
+			return newObject().Proc(interp, args)
+		}
+
+		fmt.Fprintln(interp.Stderr, usage)
+		return EmptyToken, ErrCommand(name, fmt.Sprintf("unknown subcommand %q", args[1].String))
+	}
+}
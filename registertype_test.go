@@ -0,0 +1,110 @@
+package adz
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type regPoint struct {
+	X     int      `json:"x" help:"horizontal position"`
+	Y     int      `json:"y"`
+	Label string   `json:"label"`
+	Tags  []string `json:"tags"`
+}
+
+func (p *regPoint) Move(dx, dy int) { p.X += dx; p.Y += dy }
+
+func (p regPoint) Dist() int { return p.X*p.X + p.Y*p.Y }
+
+func TestRegisterType_New(t *testing.T) {
+	interp := NewInterp()
+	if err := RegisterType[regPoint](interp, "Point", TypeHelp("A point on the plane.")); err != nil {
+		t.Fatal(err)
+	}
+
+	runScripts(t, interp, []scriptTest{
+		{`[Point new] Dist`, "0"},
+		{`set p [Point new -x 3 -Y 4]; $p Dist`, "25"},
+		{`$p Move 1 1; $p .X`, "4"},
+		{`[Point new -tags {a b}] .tags.1`, "b"},
+		{`[Point fromjson {"x": 1, "label": "one"}] .label`, "one"},
+		{`Point fields`, "X Y Label Tags"},
+		{`Point methods`, "Dist Move"},
+	})
+
+	for _, script := range []string{
+		`Point new -x`,
+		`Point new -z 1`,
+		`Point new -x notanint`,
+		`Point fromjson {"z": 1}`,
+		`Point fromjson {[1, 2]}`,
+		`Point bogus`,
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestRegisterType_Handle(t *testing.T) {
+	interp := NewInterp()
+	RegisterType[regPoint](interp, "Point")
+
+	p1 := mustRun(t, interp, `Point new`)
+	p2 := mustRun(t, interp, `Point new`)
+	if p1 == p2 || p1 == "Point" {
+		t.Errorf("expected distinct handles, got %q and %q", p1, p2)
+	}
+
+	// once Data is lost, the handle is no longer a command rather than
+	// running the type's
+	_, err := interp.ExecString(p1 + " Dist")
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("expected command not found, got %v", err)
+	}
+}
+
+func TestRegisterType_Help(t *testing.T) {
+	interp := NewInterp()
+	RegisterType[regPoint](interp, "Point",
+		TypeHelp("A point on the plane."),
+		TypeFieldHelp("Label", "what to call it"),
+		TypeMethod("Move", NewArgSet("Move",
+			ArgHelp("dx", "change in x"),
+			ArgHelp("dy", "change in y"),
+		)),
+	)
+
+	out, err := interp.ExecString(`[Point new] help`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"A point on the plane.",
+		"Methods:",
+		"Dist -> int",
+		"Move  dx  dy",
+		"Fields:",
+		".X int",
+		"horizontal position",
+		".Label string",
+		"what to call it",
+	} {
+		if !strings.Contains(out.String, want) {
+			t.Errorf("expected help to contain %q, got:\n%s", want, out.String)
+		}
+	}
+
+	out, err = interp.ExecString(`[Point new] help Move`)
+	if err != nil || !strings.Contains(out.String, "change in x") {
+		t.Errorf("expected Move's ArgSet help, got %q, %v", out.String, err)
+	}
+	out, err = interp.ExecString(`[Point new] help .x`)
+	if err != nil || !strings.Contains(out.String, "horizontal position") {
+		t.Errorf("expected X's help, got %q, %v", out.String, err)
+	}
+	if _, err := interp.ExecString(`[Point new] help Nope`); err == nil {
+		t.Errorf("expected error for unknown help target")
+	}
+}
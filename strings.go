@@ -51,44 +51,45 @@ func LoadStringsProcs(interp *Interp) {
 	interp.LoadProcs("str", StringsProcs)
 }
 
-/*
 func init() {
-	StdLib["match"] = procMatch
+	StdLib["match"] = ProcMatch
 }
 
-func procMatch(interp *Interp, args []*Token) (*Token, error) {
-	namedProto, posProto, err := ParseProto(NewToken(`{-style glob} {-matchcase true} pattern str`))
+// match ?-style glob|regex|substr? ?-matchcase bool? pattern str
+func ProcMatch(interp *Interp, args []*Token) (*Token, error) {
+	as := NewArgSet(args[0].String,
+		&Argument{
+			Name:    "-style",
+			Help:    "How {pattern} is interpreted: a glob, a regular expression or a substring.",
+			Default: NewToken("glob"),
+			Coerce:  NewToken("tuple {glob regex substr}"),
+		},
+		&Argument{
+			Name:    "-matchcase",
+			Help:    "Whether or not to be case sensitive in matching.",
+			Default: TrueToken,
+			Coerce:  Proc(ProcBool).AsToken("bool"),
+		},
+		ArgHelp("pattern", "The pattern to match."),
+		ArgHelp("str", "The string to match against."),
+	)
+	as.Help = "Reports whether {str} matches {pattern}."
+
+	parsedArgs, err := as.BindArgs(interp, args)
 	if err != nil {
+		as.ShowUsage(interp.Stderr)
 		return EmptyToken, err
 	}
 
-	parsedArgs, err := ParseArgs(namedProto, posProto, args[1:])
+	match, err := stringMatcher(parsedArgs["style"].String, parsedArgs["pattern"], parsedArgs["matchcase"].Data.(bool))
 	if err != nil {
 		return EmptyToken, err
 	}
-
-	pattern, str := parsedArgs["pattern"].String, parsedArgs["str"].String
-	if !parsedArgs["matchcase"].IsTrue() {
-		pattern = strings.ToLower(pattern)
-		str = strings.ToLower(str)
-	}
-
-	switch parsedArgs["style"].String {
-	case "substr":
-		return NewToken(strings.Contains(str, pattern)), nil
-	case "regex":
-		return EmptyToken, ErrNotImplemented("regex")
-	case "glob":
-		m, err := filepath.Match(pattern, str)
-		if err != nil {
-			return EmptyToken, fmt.Errorf("match glob error: %w", err)
-		}
-		return NewToken(m), nil
-	default:
-		return EmptyToken, fmt.Errorf("invalid match style: %s", parsedArgs["style"].String)
+	if match(parsedArgs["str"].String) {
+		return TrueToken, nil
 	}
+	return FalseToken, nil
 }
-*/
 
 // --- helpers ---------------------------------------------------------------
 
@@ -1,20 +1,47 @@
 package adz
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/sparques/adz/parser"
 )
 
+// substWord is Subst for the words of a script. A braced word's literal is
+// kept in the word's Data, so that what commands cache in it, like a parsed
+// body or expression, is still there the next time the word is run.
+func (interp *Interp) substWord(word *Token) (*Token, error) {
+	if lit, ok := word.Data.(*Token); ok {
+		return lit, nil
+	}
+	if !isBraced(word.String) {
+		return interp.Subst(word)
+	}
+	lit := NewTokenString(word.String[1 : len(word.String)-1])
+	if word.Data == nil {
+		word.Data = lit
+	}
+	return lit, nil
+}
+
+// isBraced reports whether str is a single braced literal.
+func isBraced(str string) bool {
+	return len(str) > 1 && str[0] == '{' && parser.FindMate(str, '{', '}') == len(str)-1
+}
+
 func (interp *Interp) Subst(tok *Token) (*Token, error) {
+	interp, leave := interp.enter()
+	defer leave()
 
 	// DON'T MODIFY tok !!!
 	switch {
 	case len(tok.String) <= 1:
 		return tok, nil
-	case tok.String[0] == '{' && parser.FindMate(tok.String, '{', '}') == len(tok.String)-1:
+	case isBraced(tok.String):
 		// we have a literal, remove brackets and return
 		return NewTokenString(tok.String[1 : len(tok.String)-1]), nil
 	case tok.String[0] == '"' && parser.FindPair(tok.String, '"') == len(tok.String)-1:
@@ -28,7 +55,7 @@ func (interp *Interp) Subst(tok *Token) (*Token, error) {
 		return interp.ExecString(tok.String[1 : len(tok.String)-1])
 	case tok.String[0] == '$' && getVarEndIndex(tok.String) == len(tok.String):
 		// whole token is a variable; return the reference variable
-		return interp.GetVar(parseVarName(tok.String))
+		return interp.lookupVar(tok.String)
 	}
 
 	str := strings.Builder{}
@@ -96,7 +123,7 @@ func (interp *Interp) Subst(tok *Token) (*Token, error) {
 
 		case '$':
 			mIdx = getVarEndIndex(tok.String[i:])
-			lookup, err := interp.GetVar(parseVarName(tok.String[i : i+mIdx]))
+			lookup, err := interp.lookupVar(tok.String[i : i+mIdx])
 			if err != nil {
 				return EmptyToken, fmt.Errorf("could not lookup var %s: %w", parseVarName(tok.String[i:i+mIdx]), err)
 			}
@@ -141,15 +168,163 @@ func getVarEndIndex(str string) (idx int) {
 		return parser.FindMate(str[1:], '{', '}') + 2
 	}
 
-	// otherwise, var name ends at first non-name char
+	// otherwise, var name ends at first non-name char, or after the closing
+	// paren of an element reference like $name(key)
 
 	// TODO: whitelist instead of blacklist??
-	idx = strings.IndexAny(str[1:], ";[\\ $\n\t") + 1
-	if idx == 0 {
-		return len(str)
+	for idx = 1; idx < len(str); idx++ {
+		switch str[idx] {
+		case ';', '[', '\\', ' ', '$', '\n', '\t':
+			return idx
+		case '(':
+			if end := parser.FindMate(str[idx:], '(', ')'); end != -1 {
+				return idx + end + 1
+			}
+		}
+	}
+
+	return len(str)
+}
+
+// lookupVar returns the value of the variable reference ref, sigil included.
+// A reference of the form $name(key) returns the element key of name's value;
+// see elemIsList. The key is substituted unless the name is braced, as in
+// ${name(key)}.
+func (interp *Interp) lookupVar(ref string) (*Token, error) {
+	name := parseVarName(ref)
+	base, key, ok := splitVarElem(name)
+	if !ok {
+		return interp.GetVar(name)
+	}
+	val, err := interp.GetVar(base)
+	if err != nil {
+		return EmptyToken, err
+	}
+	keyTok := NewTokenString(key)
+	if ref[1] != '{' {
+		keyTok, err = interp.Subst(keyTok)
+		if err != nil {
+			return EmptyToken, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	elem, err := elemGet(interp.varToken(base), val, keyTok)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", base, err)
+	}
+	return elem, nil
+}
+
+// setVarElem sets the element key of the variable name to val, creating the
+// variable if needed, and stores the updated value back through SetVar.
+func (interp *Interp) setVarElem(name string, key, val *Token) (*Token, error) {
+	cur, err := interp.GetVar(name)
+	if err != nil {
+		if !errors.Is(err, ErrNoVar) {
+			return EmptyToken, err
+		}
+		cur = EmptyToken
+	}
+	updated, err := elemSet(interp.varToken(name), cur, key, val)
+	if err != nil {
+		return EmptyToken, fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := interp.SetVar(name, updated); err != nil {
+		return EmptyToken, err
+	}
+	return val, nil
+}
+
+// varToken returns the token the variable name holds, as GetVar finds it but
+// without following a Getter, or nil if there's none.
+func (interp *Interp) varToken(name string) *Token {
+	if isQualified(name) {
+		ns, id, err := interp.ResolveIdentifier(name, false)
+		if err != nil {
+			return nil
+		}
+		return ns.Vars[id]
+	}
+	return interp.Frame.localVars[name]
+}
+
+// splitVarElem splits a variable name of the form name(key).
+func splitVarElem(name string) (base, key string, ok bool) {
+	open := strings.IndexByte(name, '(')
+	if open < 1 || name[len(name)-1] != ')' {
+		return name, "", false
+	}
+	return name[:open], name[open+1 : len(name)-1], true
+}
+
+// elemGet returns the element key of val, the value of the variable held by
+// raw.
+func elemGet(raw, val, key *Token) (*Token, error) {
+	if elemIsList(raw, val, key) {
+		list, _ := val.AsList()
+		idx, err := elemIndex(list, key)
+		if err != nil {
+			return EmptyToken, err
+		}
+		return list[idx], nil
+	}
+	return dictGetPath(val, []*Token{key})
+}
+
+// elemSet returns a copy of val, the value of the variable held by raw, with
+// the element key set to elem.
+func elemSet(raw, val, key, elem *Token) (*Token, error) {
+	if elemIsList(raw, val, key) {
+		list, err := val.AsList()
+		if err != nil {
+			return EmptyToken, ErrExpectedList(val.String)
+		}
+		idx, err := elemIndex(list, key)
+		if err != nil {
+			return EmptyToken, err
+		}
+		list = slices.Clone(list)
+		list[idx] = elem
+		return NewList(list), nil
+	}
+	return dictUpdatePath(val, []*Token{key}, func(d *Dict, k string) {
+		d.Set(k, elem)
+	})
+}
+
+// elemIsList reports whether the element key of val, the value of the
+// variable held by raw, is indexed as a list rather than by key. A dict, as
+// dict and setting elements by key make, is indexed by key, as is anything
+// when key isn't an integer; otherwise a non-empty list is indexed as a
+// list, as is a Go slice linked with LinkVar.
+func elemIsList(raw, val, key *Token) bool {
+	if raw != nil {
+		if link, ok := raw.Data.(*Link); ok {
+			v := link.ptr.Elem()
+			return v.Kind() == reflect.Slice && !v.Type().Implements(textMarshalerType)
+		}
+	}
+	switch val.Data.(type) {
+	case *Dict, Map:
+		return false
+	}
+	if _, err := key.AsInt(); err != nil {
+		return false
 	}
+	list, err := val.AsList()
+	return err == nil && len(list) > 0
+}
 
-	return
+// elemIndex returns key as an index of list. Unlike Index, it doesn't count
+// negative indices from the end, and an index out of range is an error.
+func elemIndex(list []*Token, key *Token) (int, error) {
+	idx, err := key.AsInt()
+	if err != nil {
+		return 0, ErrExpectedInt(key.String)
+	}
+	if idx < 0 || idx >= len(list) {
+		return 0, fmt.Errorf("index %d out of range [0,%d)", idx, len(list))
+	}
+	return idx, nil
 }
 
 func stripLiteralBrackets(str string) string {
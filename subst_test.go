@@ -75,6 +75,10 @@ var getVarEndIndexTests = []getVarEndIndexTest{
 	// 5
 	getVarEndIndexTest{"${asdf asdf} asdf", 12},
 	getVarEndIndexTest{"${asdf[asdf}", 12},
+	getVarEndIndexTest{"$a(b) c", 5},
+	getVarEndIndexTest{"$a($b(c))d", 9},
+	getVarEndIndexTest{"$a([x y]) z", 9},
+	getVarEndIndexTest{"$a(b c", 4},
 }
 
 func Test_getVarEndIndex(t *testing.T) {
@@ -85,3 +89,59 @@ func Test_getVarEndIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestSubst_Element(t *testing.T) {
+	interp := NewInterp()
+	ports := []int{80, 443}
+	if err := interp.LinkVar("ports", &ports); err != nil {
+		t.Fatal(err)
+	}
+
+	runScripts(t, interp, []scriptTest{
+		{`set d [dict create host example port 8080]; return $d(port)`, "8080"},
+		{`set k host; return "$d($k):$d(port)"`, "example:8080"},
+		{`set l {a b c}; return $l(1)`, "b"},
+		{`return $l(2)`, "c"},
+		{`return $l([+ 1 1])`, "c"},
+		{`set d(port) 9090; return $d`, "host example port 9090"},
+		{`set l(0) z; return $l`, "z b c"},
+		{`set nd(a) 1; set nd(b) 2; return $nd`, "a 1 b 2"},
+		{`set nested [dict create inner {x 1}]; return [dict get $nested(inner) x]`, "1"},
+		{`namespace ::ns { set ::ns::cfg {port 22} }; return $::ns::cfg(port)`, "22"},
+		{`set ::ns::cfg(port) 2222; return $::ns::cfg(port)`, "2222"},
+		{`return $ports(1)`, "443"},
+		{`set ports(0) 8000; return $ports`, "8000 443"},
+		{`set {braced(x)} 1; return ${braced(x)}`, "1"},
+		// a list is indexed by integer, even one that reads as a dict
+		{`set m {10 20 30 40}; return $m(1)`, "20"},
+		{`set m(0) x; return $m`, "x 20 30 40"},
+		{`set m {10 20 30 40}; dict get $m 10; return $m(1)`, "20"},
+		// while a dict is indexed by key, even an integer
+		{`set di [dict create 1 x 2 y]; set di(2) Z; return $di`, "1 x 2 Z"},
+		{`return $di(1)`, "x"},
+		{`set m {a 1 b 2}; return $m(b)`, "2"},
+	})
+
+	if ports[0] != 8000 {
+		t.Errorf("set ports(0) didn't write through the link: %v", ports)
+	}
+
+	for _, script := range []string{
+		`return $d(nosuch)`,
+		`return ${d($k)}`, // braced keys aren't substituted
+	} {
+		if _, err := interp.ExecString(script); err == nil {
+			t.Errorf("%s: expected error for missing dict key", script)
+		}
+	}
+
+	runScriptErrors(t, interp, []scriptTest{
+		{`set l {a b c}; set l(5) X`, "index 5 out of range"},
+		{`set l(-1) X`, "index -1 out of range"},
+		{`return $l(3)`, "index 3 out of range"},
+		{`set ports(2) 1`, "index 2 out of range"},
+	})
+	if out := mustRun(t, interp, `return $l`); out != "a b c" {
+		t.Errorf("failed sets changed the list: %q", out)
+	}
+}
@@ -24,7 +24,7 @@ func Test_Tailcall(t *testing.T) {
 
 		proc fibtc {n n1 n2} {
 			if {or [== $n 1] [== $n1 0]} {return $n2}
-			tailcall [+ $n [int -1]] [+ $n1 $n2] $n1
+			tailcall fibtc [+ $n [int -1]] [+ $n1 $n2] $n1
 		}
 
 		fibtc 50 1 1
@@ -40,3 +40,68 @@ func Test_Tailcall(t *testing.T) {
 		t.Errorf("tailcall: expected 12586269025, got %s", out.String)
 	}
 }
+
+func Test_Tailcall_Mutual(t *testing.T) {
+	interp := NewInterp()
+	// far deeper than MaxCallDepth, so this only works in constant stack
+	out, err := interp.ExecString(`
+		proc isEven {n} { if {== $n 0} {return true}; tailcall isOdd [- $n 1] }
+		proc isOdd {n} { if {== $n 0} {return false}; tailcall isEven [- $n 1] }
+		list [isEven 10000] [isOdd 10001] [isOdd 10000]
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String != "true true false" {
+		t.Errorf("expected %q, got %q", "true true false", out.String)
+	}
+	if interp.CallDepth() != 0 || len(interp.Stack) != 0 {
+		t.Errorf("expected empty stack, got depth %d and %d frames", interp.CallDepth(), len(interp.Stack))
+	}
+}
+
+func Test_Tailcall_StateMachine(t *testing.T) {
+	interp := NewInterp()
+	// three states cycling, each adding its own amount
+	out, err := interp.ExecString(`
+		proc ping {n acc} { if {== $n 0} {return $acc}; tailcall pong [- $n 1] [+ $acc 1] }
+		proc pong {n acc} { if {== $n 0} {return $acc}; tailcall pang [- $n 1] [+ $acc 2] }
+		proc pang {n acc} { if {== $n 0} {return $acc}; tailcall ping [- $n 1] [+ $acc 3] }
+		ping 3000 0
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String != "6000" {
+		t.Errorf("expected 6000, got %q", out.String)
+	}
+}
+
+func Test_Tailcall_AnyCommand(t *testing.T) {
+	runScripts(t, nil, []scriptTest{
+		// Go commands
+		{`proc f {} { tailcall list a b }; f`, "a b"},
+		{`proc f {} { tailcall return early; return late }; list [f] after`, "early after"},
+		// the caller's frame is gone when the command runs
+		{`proc g {} { catch {set y $x} }; proc f {x} { tailcall g }; f 1`, "true"},
+		// but the command is resolved while it's still there
+		{`proc f {} { proc local {x} {return local$x}; tailcall local 1 }; f`, "local1"},
+		// arguments are substituted in the caller
+		{`proc f {x} { tailcall list $x [+ $x 1] }; f 1`, "1 2"},
+		// anonymous procs and closures
+		{`proc f {} { tailcall [proc {x} {return anon$x}] 1 }; f`, "anon1"},
+		{`proc f {n} { tailcall [closure -vars n {} {return $n}] }; f 7`, "7"},
+		// methods
+		{`class define C { method m {n} { if {== $n 0} {return done}; tailcall $self m [- $n 1] } }; set o [C new]; $o m 5000`, "done"},
+		// a proc tailcalled from inside a callback doesn't end the callback's caller
+		{`proc id {x} { return $x }; proc f {x} { tailcall id $x }; list::map {a b} f`, "a b"},
+	})
+
+	interp := NewInterp()
+	if _, err := interp.ExecString(`proc f {} { tailcall nosuchcmd }; f`); err == nil {
+		t.Error("expected error tailcalling a missing command")
+	}
+	if _, err := interp.ExecString(`proc f {} { tailcall }; f`); err == nil {
+		t.Error("expected error from tailcall without a command")
+	}
+}
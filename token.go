@@ -87,11 +87,12 @@ type Ref struct {
 	Namespace *Namespace
 }
 
-// Token generates a token with it's .Data set to the ref
+// Token generates a token with it's .Data set to the ref. If the ref doesn't
+// resolve yet, the token's String is empty.
 func (r *Ref) Token() *Token {
 	target, err := r.Get(nil)
 	if err != nil {
-		return EmptyToken
+		return &Token{Data: r}
 	}
 	return &Token{
 		String: target.String,
@@ -278,23 +279,22 @@ func (tok *Token) IsTrue() bool {
 	return b
 }
 
+// AsInt interprets tok as an integer literal; see parseNumber for the
+// syntax. Integers too big for an int are an error.
 func (tok *Token) AsInt() (int, error) {
 	if val, ok := tok.Data.(int); ok {
 		return val, nil
 	}
-	var val int
-	n, err := fmt.Sscan(tok.String, &val)
-	// val, err := strconv.ParseInt(tok.String, 10, 64)
-	if err != nil {
-		return 0, err
-	}
-	if n != 1 {
-		return 0, fmt.Errorf("could not parse %v as int", tok.String)
+	n, ok := parseNumber(tok.String)
+	if !ok || n.isFloat || n.big != nil {
+		return 0, ErrExpectedInt(tok.String)
 	}
-	tok.Data = val
-	return val, err
+	tok.Data = n.i
+	return n.i, nil
 }
 
+// AsFloat interprets tok as a numeric literal, integer or float; see
+// parseNumber for the syntax.
 func (tok *Token) AsFloat() (float64, error) {
 	if val, ok := tok.Data.(float64); ok {
 		return val, nil
@@ -302,12 +302,14 @@ func (tok *Token) AsFloat() (float64, error) {
 	if num, ok := tok.Data.(Floater); ok {
 		return num.Float(), nil
 	}
-	val, err := strconv.ParseFloat(tok.String, 64)
-	if err != nil {
-		return 0, err
+	n, ok := parseNumber(tok.String)
+	if !ok {
+		return 0, ErrExpectedNumber(tok.String)
+	}
+	if n.isFloat {
+		tok.Data = n.f
 	}
-	tok.Data = val
-	return val, err
+	return n.Float64(), nil
 }
 
 // AsTuple ensures that tok is equal to one of the values in list
@@ -336,29 +338,46 @@ func (tok *Token) AsScript() (Script, error) {
 	return tok.Data.(Script), err
 }
 
+// AsProc returns tok as a proc. Unless it already is one, tok is parsed as a
+// list of {args body ?namespace?}: an argument prototype, a body, and the
+// namespace the proc runs in, relative to the global namespace, which
+// defaults to the current one. The proc is given a name from Monotonic, as
+// anonymous procs are, and cached in tok's Data.
 func (tok *Token) AsProc(interp *Interp) (Proc, error) {
-	// if already cached as Proc, just return it
-	if proc, ok := tok.Data.(Procer); ok {
-		return proc.Proc, nil
-	}
-	// otherwise try to parse as two element list.
+	// if already cached as Proc, just return it. A List is a Procer, but
+	// one holding {args body} still needs making into a proc.
+	switch v := tok.Data.(type) {
+	case Proc:
+		return v, nil
+	case List:
+	case Procer:
+		return v.Proc, nil
+	}
+	// otherwise try to parse as two or three element list.
 	// First element is the argument prototype.
 	// Second element is the proc body.
+	// Third, if given, is the namespace.
 	argproc, err := tok.AsList()
 	if err != nil {
 		return nil, fmt.Errorf("could not parse as list")
 	}
-	if len(argproc) != 2 {
-		return nil, fmt.Errorf("list does not contain two elements")
+	if len(argproc) != 2 && len(argproc) != 3 {
+		return nil, fmt.Errorf("list does not contain two or three elements")
 	}
-	// TODO: generate monotonic names for anonymous procs e.g. proc#1
-	pTok, err := ProcProc(interp, []*Token{tok, argproc[0], argproc[1]})
+	ns := interp.Frame.localNamespace
+	if len(argproc) == 3 {
+		ns, _, err = interp.ResolveIdentifier("::"+strings.TrimPrefix(argproc[2].String, "::")+"::", true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	proc, err := newProc(ns, interp.Monotonic.Next("proc"), argproc[:2], nil)
 	if err != nil {
 		return nil, fmt.Errorf("could not create proc from token: %w", err)
 	}
-	tok.Data = pTok.Data
+	tok.Data = Proc(proc)
 
-	return pTok.Data.(Proc), nil
+	return proc, nil
 }
 
 // AsCommand is similar to AsList, but doesn't overwrite the underlaying
@@ -386,18 +405,6 @@ func NewList(s []*Token) *Token {
 	t, _ := List(s).MarshalToken()
 
 	return t
-
-	list := &Token{
-		Data: List(s),
-	}
-
-	list.String = s[0].Quoted()
-
-	for i := 1; i < len(s); i++ {
-		list.String += " " + s[i].Quoted()
-	}
-
-	return list
 }
 
 type List []*Token
@@ -407,17 +414,7 @@ func (l List) Len() int {
 }
 
 func (l List) Less(i, j int) bool {
-	switch v := l[i].Data.(type) {
-	case int:
-		w, ok := l[j].Data.(int)
-		if ok {
-			return v < w
-		} else {
-			return l[i].String < l[j].String
-		}
-	default:
-		return l[i].String < l[j].String
-	}
+	return l[i].Compare(l[j]) < 0
 }
 
 func (l List) Swap(i, j int) {
@@ -442,6 +439,9 @@ func (tok *Token) AsList() (list []*Token, err error) {
 	if list, ok := tok.Data.(List); ok {
 		return list, nil
 	}
+	if d, ok := tok.Data.(*Dict); ok {
+		return d.List(), nil
+	}
 	if len(tok.String) == 0 {
 		return EmptyList, nil
 	}
@@ -596,12 +596,3 @@ func contains(s []*Token, e *Token) bool {
 	}
 	return false
 }
-
-func (tok *Token) Equal(c *Token) bool {
-	// TODO, more thorough means of comparison.
-	// Maybe check if .Data implements an Equal method?
-	if eq, ok := tok.Data.(Equivalence); ok {
-		return eq.Equal(c)
-	}
-	return tok.String == c.String
-}
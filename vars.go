@@ -18,6 +18,10 @@ func ProcSet(interp *Interp, args []*Token) (*Token, error) {
 		return EmptyToken, ErrArgCount(2, len(args)-1)
 	}
 
+	if name, key, ok := splitVarElem(args[1].String); ok {
+		return interp.setVarElem(name, NewTokenString(key), args[2])
+	}
+
 	return interp.SetVar(args[1].String, args[2])
 }
 
@@ -242,18 +246,3 @@ func (interp *Interp) getVarRef(varName string) (ref *Ref, err error) {
 
 	return nil, fmt.Errorf("%w: %s", ErrNoVar, varName)
 }
-
-/*
-func (interp *Interp) Anoint(varName, className string) (*Token, error) {
-	// if variable already exists just use it
-	tok, err := interp.GetVar(varName)
-	if err != nil {
-		if !errors.Is(err, ErrNoVar) {
-			return nil, fmt.Errorf("cannot anoint %s with %s: %w", varName, className, err)
-		}
-		tok = NewToken("")
-	}
-	defer interp.SetVar(varName, tok)
-
-}
-*/
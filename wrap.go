@@ -3,167 +3,50 @@ package adz
 import (
 	"fmt"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
 )
 
-// helper: build a tuple coercer token: ["tuple", ["M1","M2",...]]
-func tupleCoercer(methods []string) *Token {
-	return NewList(
-		[]*Token{
-			NewTokenString("tuple"),
-			NewList(NewTokenListString(methods)),
-		})
-}
-
 // Wrap takes any value v and returns a *Token that prints like v and
-// is invocable as a Proc: [$obj <Method> arg ...]. Method is validated
-// via ArgSet using a tuple coercer that lists all valid methods.
+// is invocable as a Proc: [$obj <Method> arg ...] or [$obj .Field ?value?].
+// It's a GoObject with the default options; see WrapObject.
 func Wrap(v any) *Token {
-	recv := reflect.ValueOf(v)
-
-	// Build method table (exported methods)
-	methods := make(map[string]reflect.Value)
-	addMethods := func(rv reflect.Value) {
-		rt := rv.Type()
-		for i := 0; i < rt.NumMethod(); i++ {
-			m := rt.Method(i)
-			methods[m.Name] = rv.Method(i) // bound
-		}
-	}
-	addMethods(recv)
-	if recv.CanAddr() {
-		addMethods(recv.Addr())
-	} else if recv.Kind() != reflect.Pointer {
-		ptr := reflect.New(recv.Type())
-		ptr.Elem().Set(recv)
-		addMethods(ptr)
-	}
-
-	// Build ArgSet: method (tuple-coerced) + variadic args
-	names := make([]string, 0, len(methods))
-	for n := range methods {
-		names = append(names, n)
+	return &Token{
+		String: fmt.Sprintf("%T", v),
+		Data:   newGoObject(v, nil),
 	}
-	sort.Strings(names)
-
-	as := NewArgSet(fmt.Sprintf("%T", v),
-		&Argument{
-			Name:   "method",
-			Coerce: tupleCoercer(names), // validates and prints allowed methods
-			Help:   "Method name",
-		},
-		&Argument{
-			Name: "args", // marks variadic positional
-			Help: "Arguments passed to the method",
-		},
-	)
-
-	// no extra validation required, but ensures all flags have been set correctly
-	_ = as.Validate()
-
-	proc := Proc(func(interp *Interp, argv []*Token) (*Token, error) {
-		// Bind via ArgSet so method is validated and "args" collected
-		bound, err := as.BindArgs(interp, argv)
-		if err != nil {
-			as.ShowUsage(interp.Stderr)
-			return EmptyToken, err
-		}
-
-		methodName := bound["method"].String
-		m := methods[methodName]
-		mt := m.Type()
-
-		// Unpack positional tail as the method's arguments
-		var userArgs []*Token
-		if atoks, ok := bound["args"]; ok && atoks != nil {
-			if lst, err := atoks.AsList(); err == nil {
-				userArgs = lst
-			} else {
-				// if not a list, treat as single
-				userArgs = []*Token{atoks}
-			}
-		}
-
-		argc := mt.NumIn()
-		if !mt.IsVariadic() {
-			if len(userArgs) != argc {
-				return EmptyToken, ErrArgCount(argc, len(userArgs))
-			}
-		} else {
-			min := argc - 1
-			if len(userArgs) < min {
-				return EmptyToken, ErrArgMinimum(min, len(userArgs))
-			}
-		}
-
-		in := make([]reflect.Value, 0, len(userArgs))
-		if mt.IsVariadic() {
-			// fixed prefix
-			for i := 0; i < argc-1; i++ {
-				val, err := convertTokenTo(userArgs[i], mt.In(i))
-				if err != nil {
-					return EmptyToken, fmt.Errorf("arg %d: %w", i+1, err)
-				}
-				in = append(in, val)
-			}
-			// variadic tail
-			sliceType := mt.In(argc - 1) // []T
-			elemType := sliceType.Elem()
-			nTail := len(userArgs) - (argc - 1)
-			varSlice := reflect.MakeSlice(sliceType, nTail, nTail)
-			for i := 0; i < nTail; i++ {
-				val, err := convertTokenTo(userArgs[(argc-1)+i], elemType)
-				if err != nil {
-					return EmptyToken, fmt.Errorf("arg %d: %w", (argc-1)+i+1, err)
-				}
-				varSlice.Index(i).Set(val)
-			}
-			in = append(in, varSlice)
-		} else {
-			for i := 0; i < argc; i++ {
-				val, err := convertTokenTo(userArgs[i], mt.In(i))
-				if err != nil {
-					return EmptyToken, fmt.Errorf("arg %d: %w", i+1, err)
-				}
-				in = append(in, val)
-			}
-		}
-
-		// Call the method
-		out := m.Call(in)
+}
 
-		// Handle trailing error
-		if n := len(out); n > 0 {
-			if out[n-1].Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-				if !out[n-1].IsNil() {
-					return EmptyToken, fmt.Errorf("%v", out[n-1].Interface().(error))
-				}
-				out = out[:n-1]
+// callResults maps the return values of a reflected call onto the usual
+// (*Token, error) convention: a trailing error is split off and returned,
+// no other values is an EmptyToken, one value is returned as is and
+// several values are returned as a list.
+func callResults(out []reflect.Value) (*Token, error) {
+	if n := len(out); n > 0 {
+		if out[n-1].Type().Implements(errorType) {
+			if !out[n-1].IsNil() {
+				return EmptyToken, out[n-1].Interface().(error)
 			}
+			out = out[:n-1]
 		}
+	}
 
-		switch len(out) {
-		case 0:
-			return EmptyToken, nil
-		case 1:
-			return wrapReturn(out[0].Interface()), nil
-		default:
-			toks := make([]*Token, len(out))
-			for i := range out {
-				toks[i] = wrapReturn(out[i].Interface())
-			}
-			return NewList(toks), nil
+	switch len(out) {
+	case 0:
+		return EmptyToken, nil
+	case 1:
+		return wrapReturn(out[0].Interface()), nil
+	default:
+		toks := make([]*Token, len(out))
+		for i := range out {
+			toks[i] = wrapReturn(out[i].Interface())
 		}
-	})
-
-	return &Token{
-		String: fmt.Sprintf("%T", v),
-		Data:   proc,
+		return NewList(toks), nil
 	}
 }
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 func convertTokenTo(tok *Token, dst reflect.Type) (reflect.Value, error) {
 	// any / interface{}
 	var srcIface any
@@ -192,6 +75,10 @@ func convertTokenTo(tok *Token, dst reflect.Type) (reflect.Value, error) {
 		if val.IsValid() && val.Type().ConvertibleTo(dst) {
 			return val.Convert(dst), nil
 		}
+		// a GoObject always holds a *T; accept it where a T is wanted
+		if val.IsValid() && val.Kind() == reflect.Pointer && !val.IsNil() && val.Type().Elem().AssignableTo(dst) {
+			return val.Elem(), nil
+		}
 	}
 	switch dst.Kind() {
 	case reflect.String: